@@ -0,0 +1,146 @@
+package async_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/async"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run(`awaits futures`, func(t *testing.T) {
+		f := async.Run(ctx, func(gc *generator.Controller) (interface{}, error) {
+			a, _, _ := gc.Yield(async.Go(func() (interface{}, error) {
+				return 1, nil
+			}))
+			b, _, _ := gc.Yield(async.Resolved(2))
+			c, _, _ := gc.Yield(3)
+			return a.(int) + b.(int) + c.(int), nil
+		})
+		if v, err := f.Await(ctx); v != 6 || err != nil {
+			t.Fatalf("got: %v, %v. wanted: 6, <nil>", v, err)
+		}
+	})
+	t.Run(`rejected future is thrown`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		f := async.Run(ctx, func(gc *generator.Controller) (interface{}, error) {
+			if _, _, err := gc.Yield(async.Rejected(e1)); err != e1 {
+				return nil, fmt.Errorf("got: %v. wanted: e1", err)
+			}
+			return "recovered", nil
+		})
+		if v, err := f.Await(ctx); v != "recovered" || err != nil {
+			t.Fatalf("got: %v, %v. wanted: recovered, <nil>", v, err)
+		}
+	})
+	t.Run(`Controller.Error rejects`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		stopped := make(chan bool, 1)
+		f := async.Run(ctx, func(gc *generator.Controller) (interface{}, error) {
+			_, shouldReturn, _ := gc.Error(e1)
+			stopped <- shouldReturn
+			return nil, nil
+		})
+		if v, err := f.Await(ctx); v != nil || err != e1 {
+			t.Fatalf("got: %v, %v. wanted: <nil>, e1", v, err)
+		}
+		if !<-stopped {
+			t.Fatal("the generator was not stopped")
+		}
+	})
+	t.Run(`context cancellation`, func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		pending, _, _ := async.Promise()
+		f := async.Run(cctx, func(gc *generator.Controller) (interface{}, error) {
+			_, _, err := gc.Yield(pending)
+			return nil, err
+		})
+		cancel()
+		if _, err := f.Await(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("got: %v. wanted: %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestPromise(t *testing.T) {
+	ctx := context.Background()
+	f, resolve, reject := async.Promise()
+	resolve(1)
+	reject(fmt.Errorf("e1"))
+	resolve(2)
+	if v, err := f.Await(ctx); v != 1 || err != nil {
+		t.Fatalf("got: %v, %v. wanted: 1, <nil>", v, err)
+	}
+
+	pending, _, _ := async.Promise()
+	cctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	if _, err := pending.Await(cctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got: %v. wanted: %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestAll(t *testing.T) {
+	ctx := context.Background()
+
+	slow, resolveSlow, _ := async.Promise()
+	f := async.All(slow, async.Resolved(2), async.Resolved(nil))
+	resolveSlow(1)
+	v, err := f.Await(ctx)
+	if want := []interface{}{1, 2, nil}; !reflect.DeepEqual(v, want) || err != nil {
+		t.Fatalf("got: %v, %v. wanted: %v, <nil>", v, err, want)
+	}
+
+	e1 := fmt.Errorf("e1")
+	pending, _, _ := async.Promise()
+	if _, err := async.All(pending, async.Rejected(e1)).Await(ctx); err != e1 {
+		t.Fatalf("got: %v. wanted: e1", err)
+	}
+
+	if v, err := async.All().Await(ctx); len(v.([]interface{})) != 0 || err != nil {
+		t.Fatalf("got: %v, %v. wanted: [], <nil>", v, err)
+	}
+}
+
+func TestRace(t *testing.T) {
+	ctx := context.Background()
+	e1 := fmt.Errorf("e1")
+
+	pending, _, _ := async.Promise()
+	if _, err := async.Race(pending, async.Rejected(e1)).Await(ctx); err != e1 {
+		t.Fatalf("got: %v. wanted: e1", err)
+	}
+	if v, err := async.Race(pending, async.Resolved(1)).Await(ctx); v != 1 || err != nil {
+		t.Fatalf("got: %v, %v. wanted: 1, <nil>", v, err)
+	}
+
+	if v, err := async.Race().Await(ctx); v != nil || err != async.ErrNoFutures {
+		t.Fatalf("got: %v, %v. wanted: <nil>, %v", v, err, async.ErrNoFutures)
+	}
+}
+
+func TestAny(t *testing.T) {
+	ctx := context.Background()
+	e1 := fmt.Errorf("e1")
+	e2 := fmt.Errorf("e2")
+
+	if v, err := async.Any(async.Rejected(e1), async.Resolved(1)).Await(ctx); v != 1 || err != nil {
+		t.Fatalf("got: %v, %v. wanted: 1, <nil>", v, err)
+	}
+
+	_, err := async.Any(async.Rejected(e1), async.Rejected(e2)).Await(ctx)
+	if !errors.Is(err, e1) || !errors.Is(err, e2) {
+		t.Fatalf("got: %v. wanted: e1 and e2", err)
+	}
+
+	if v, err := async.Any().Await(ctx); v != nil || err != async.ErrNoFutures {
+		t.Fatalf("got: %v, %v. wanted: <nil>, %v", v, err, async.ErrNoFutures)
+	}
+}
@@ -0,0 +1,178 @@
+// Package async runs generator functions as asynchronous tasks, like
+// async functions in Javascript.
+//
+// A `Func` awaits a `*Future` by yielding it. The driver started by
+// `Run` resumes the `Func` with the future's value when it's resolved,
+// or throws the future's error into the `Func` when it's rejected:
+//
+//	f := async.Run(ctx, func(gc *generator.Controller) (interface{}, error) {
+//		user, _, err := gc.Yield(fetchUser(id))
+//		if err != nil {
+//			return nil, err
+//		}
+//		...
+//	})
+package async
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNoFutures is the error of the future returned by `Race` or `Any`
+// when it's called without futures.
+var ErrNoFutures = errors.New("async: no futures")
+
+// Future is a value that will be available in the future. It's either
+// resolved with a value or rejected with an error, and it can only be
+// settled once.
+type Future struct {
+	once  sync.Once
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+// Promise creates a pending future together with the functions that
+// settle it. Only the first call to either of the functions has an
+// effect.
+func Promise() (f *Future, resolve func(value interface{}), reject func(err error)) {
+	f = newFuture()
+	resolve = func(value interface{}) {
+		f.settle(value, nil)
+	}
+	reject = func(err error) {
+		f.settle(nil, err)
+	}
+	return f, resolve, reject
+}
+
+// Resolved returns a future that is resolved with the value.
+func Resolved(value interface{}) *Future {
+	f := newFuture()
+	f.settle(value, nil)
+	return f
+}
+
+// Rejected returns a future that is rejected with the error.
+func Rejected(err error) *Future {
+	f := newFuture()
+	f.settle(nil, err)
+	return f
+}
+
+// Go runs fn in a new goroutine and returns a future that is resolved
+// with the value returned by fn, or rejected with its error if it's not
+// nil.
+func Go(fn func() (interface{}, error)) *Future {
+	f := newFuture()
+	go func() {
+		f.settle(fn())
+	}()
+	return f
+}
+
+func (f *Future) settle(value interface{}, err error) {
+	f.once.Do(func() {
+		f.value, f.err = value, err
+		close(f.done)
+	})
+}
+
+// Done returns a channel that is closed when the future is settled.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await waits for the future to be settled and returns its value and
+// error. If ctx is done first, it returns the context's error.
+func (f *Future) Await(ctx context.Context) (interface{}, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// All returns a future that is resolved with a `[]interface{}` of the
+// values of the futures, in the same order, once all of them are
+// resolved. It's rejected with the error of the first future that is
+// rejected.
+func All(futures ...*Future) *Future {
+	f := newFuture()
+	go func() {
+		settled := settledOrder(futures)
+		values := make([]interface{}, len(futures))
+		for range futures {
+			i := <-settled
+			if err := futures[i].err; err != nil {
+				f.settle(nil, err)
+				return
+			}
+			values[i] = futures[i].value
+		}
+		f.settle(values, nil)
+	}()
+	return f
+}
+
+// Race returns a future that is settled in the same way as the first of
+// the futures to be settled. It's rejected with `ErrNoFutures` if there
+// are no futures.
+func Race(futures ...*Future) *Future {
+	if len(futures) == 0 {
+		return Rejected(ErrNoFutures)
+	}
+	f := newFuture()
+	go func() {
+		i := <-settledOrder(futures)
+		f.settle(futures[i].value, futures[i].err)
+	}()
+	return f
+}
+
+// Any returns a future that is resolved with the value of the first of
+// the futures to be resolved. If all of them are rejected, it's rejected
+// with the errors of the futures joined in the same order. It's rejected
+// with `ErrNoFutures` if there are no futures.
+func Any(futures ...*Future) *Future {
+	if len(futures) == 0 {
+		return Rejected(ErrNoFutures)
+	}
+	f := newFuture()
+	go func() {
+		settled := settledOrder(futures)
+		errs := make([]error, len(futures))
+		for range futures {
+			i := <-settled
+			if err := futures[i].err; err != nil {
+				errs[i] = err
+				continue
+			}
+			f.settle(futures[i].value, nil)
+			return
+		}
+		f.settle(nil, errors.Join(errs...))
+	}()
+	return f
+}
+
+// settledOrder returns a channel that receives the index of each of the
+// futures as soon as it's settled.
+func settledOrder(futures []*Future) <-chan int {
+	settled := make(chan int, len(futures))
+	for i, f := range futures {
+		i, f := i, f
+		go func() {
+			<-f.done
+			settled <- i
+		}()
+	}
+	return settled
+}
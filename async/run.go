@@ -0,0 +1,65 @@
+package async
+
+import (
+	"context"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// Run starts a generator with fn and drives it in a new goroutine. The
+// returned future is settled with the value and the error returned by
+// fn.
+//
+// Whenever fn yields a `*Future`, it's resumed when the future is
+// settled: `Controller#Yield` returns the value of a resolved future or
+// the error of a rejected one. Any other yielded value is treated as an
+// already resolved future of that value.
+//
+// If fn delivers an error using `Controller#Error`, the generator is
+// stopped with `Generator#Return` and the returned future is rejected
+// with the error.
+//
+// When ctx is done, fn receives the context's error instead of the value
+// of the future it's waiting for, as well as for any future it yields
+// after that. fn should return when it receives the error.
+func Run(ctx context.Context, fn generator.Func) *Future {
+	f := newFuture()
+	go func() {
+		g := generator.New(fn)
+
+		var (
+			sendValue interface{}
+			sendErr   error
+		)
+		for {
+			var (
+				value  interface{}
+				isDone bool
+				err    error
+			)
+			if sendErr != nil {
+				value, isDone, err = g.Error(sendErr)
+			} else {
+				value, isDone, err = g.Next(sendValue)
+			}
+
+			if isDone {
+				f.settle(value, err)
+				return
+			} else if err != nil {
+				g.Return(nil)
+				f.settle(nil, err)
+				return
+			}
+
+			if cerr := ctx.Err(); cerr != nil {
+				sendValue, sendErr = nil, cerr
+			} else if awaited, ok := value.(*Future); ok {
+				sendValue, sendErr = awaited.Await(ctx)
+			} else {
+				sendValue, sendErr = value, nil
+			}
+		}
+	}()
+	return f
+}
@@ -0,0 +1,37 @@
+package generator
+
+import "time"
+
+// Clock provides the current time and timers to the functions of this
+// package that need to wait. It can be replaced in tests so that they
+// don't have to wait in real time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for the duration to elapse and then sends the current
+	// time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the `Clock` that uses the `time` package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// clockOrSystem returns c if it isn't nil. Otherwise, it returns the
+// `SystemClock`.
+func clockOrSystem(c Clock) Clock {
+	if c != nil {
+		return c
+	}
+	return SystemClock
+}
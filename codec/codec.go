@@ -0,0 +1,144 @@
+// Package codec provides generators that decode streams of JSON, NDJSON
+// and CSV records one record at a time, and functions that encode the
+// values yielded by a generator into such streams.
+//
+// Records that can't be decoded are delivered to the consumer as
+// `Generator#Next`'s error. Unless the consumer calls `Generator#Return`
+// or `Generator#Error`, the decoder continues with the next record.
+//
+// The encoders write the values as soon as they are yielded instead of
+// collecting the whole sequence first.
+package codec
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// flushEvery is the number of records after which the encoders flush
+// their buffered output to the underlying writer.
+const flushEvery = 64
+
+// DecodeError is delivered to the consumer of a decoding generator when
+// a single record of the stream couldn't be decoded.
+type DecodeError struct {
+	// Index is the zero-based index of the record within the stream. For
+	// NDJSON streams, it's the zero-based line number of the record.
+	Index int
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("codec: record %d: %v", e.Index, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// stop is the result of a generator controller function call which
+// tells the decoder that it should stop decoding. returnValue and err
+// are the values that should be returned by the `Func`.
+type stop struct {
+	returnValue interface{}
+	err         error
+}
+
+// handle checks the result of a generator controller function call and
+// returns a non-nil `stop` when the consumer called `Generator#Return`
+// or `Generator#Error`.
+func handle(value interface{}, shouldReturn bool, err error) *stop {
+	switch {
+	case shouldReturn:
+		return &stop{returnValue: value}
+	case err != nil:
+		return &stop{err: err}
+	}
+	return nil
+}
+
+// newElemOrAny returns newElem if it isn't nil. Otherwise, it returns a
+// function that creates pointers to empty interfaces.
+func newElemOrAny(newElem func() interface{}) func() interface{} {
+	if newElem != nil {
+		return newElem
+	}
+	return func() interface{} {
+		return new(interface{})
+	}
+}
+
+// elemValue returns the value that should be yielded for the decoded
+// elem. When newElem is nil, the decoded value is yielded instead of the
+// pointer to it.
+func elemValue(newElem func() interface{}, elem interface{}) interface{} {
+	if newElem != nil {
+		return elem
+	}
+	return *(elem.(*interface{}))
+}
+
+// encode calls `Next` until g is done and calls encodeFn for each of the
+// yielded values, passing the zero-based index of the value. After g is
+// done, finishFn, if not nil, is called with the number of values that
+// were encoded. The output written to the buffered writer is flushed
+// every `flushEvery` records and after the generator is done.
+//
+// When g delivers an error or encodeFn or a flush fails, g is stopped
+// with `Return` and the error is returned. The output of the values that
+// were encoded before the error is flushed first, so the consumer of w
+// receives everything that g yielded up to that point.
+func encode(
+	w io.Writer,
+	g *generator.Generator,
+	encodeFn func(bw *bufio.Writer, i int, value interface{}) error,
+	finishFn func(bw *bufio.Writer, n int) error,
+) error {
+	bw := bufio.NewWriter(w)
+
+	for i := 0; ; i++ {
+		value, isDone, err := g.Next(nil)
+		if isDone {
+			if err != nil {
+				flush(w, bw)
+				return err
+			}
+			if finishFn != nil {
+				if err := finishFn(bw, i); err != nil {
+					return err
+				}
+			}
+			return flush(w, bw)
+		}
+
+		if err == nil {
+			err = encodeFn(bw, i, value)
+		}
+		if err == nil && (i+1)%flushEvery == 0 {
+			err = flush(w, bw)
+		}
+		if err != nil {
+			g.Return(nil)
+			flush(w, bw)
+			return err
+		}
+	}
+}
+
+// flush writes the buffered output to w. If w can also be flushed, like
+// an `http.ResponseWriter` or a `*gzip.Writer`, it's flushed as well.
+func flush(w io.Writer, bw *bufio.Writer) error {
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
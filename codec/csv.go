@@ -0,0 +1,156 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// Record is the value yielded by the generator created by `CSV`.
+type Record struct {
+	// Header is the first record of the CSV stream. It's shared by all
+	// of the records of the stream so it shouldn't be modified.
+	Header []string
+
+	// Fields are the fields of the record.
+	Fields []string
+
+	// Line is the line number where the record starts.
+	Line int
+}
+
+// Get returns the field of the record under the column named name. It
+// returns false if there's no such column or if the record doesn't have
+// a field for it.
+func (r Record) Get(name string) (string, bool) {
+	for i, h := range r.Header {
+		if h == name {
+			if i < len(r.Fields) {
+				return r.Fields[i], true
+			}
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// Map returns the fields of the record keyed by their column names.
+func (r Record) Map() map[string]string {
+	m := make(map[string]string, len(r.Header))
+	for i, h := range r.Header {
+		if i < len(r.Fields) {
+			m[h] = r.Fields[i]
+		}
+	}
+	return m
+}
+
+// CSV creates a generator that reads the CSV records from r one record
+// at a time using `encoding/csv`. The first record is used as the
+// header and each of the records that come after it is yielded as a
+// `Record`.
+//
+// A record that can't be parsed or doesn't have the same number of
+// fields as the header is delivered as a `*csv.ParseError` and the
+// reader continues with the next record.
+func CSV(r io.Reader) *generator.Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			cr := csv.NewReader(r)
+
+			header, err := cr.Read()
+			if err == io.EOF {
+				return nil, nil
+			} else if err != nil {
+				return nil, err
+			}
+
+			for {
+				fields, err := cr.Read()
+				if err == io.EOF {
+					return nil, nil
+				}
+
+				var s *stop
+				if err != nil {
+					var parseErr *csv.ParseError
+					if !errors.As(err, &parseErr) {
+						return nil, err
+					}
+					s = handle(gc.Error(err))
+				} else {
+					line, _ := cr.FieldPos(0)
+					s = handle(gc.Yield(Record{Header: header, Fields: fields, Line: line}))
+				}
+				if s != nil {
+					return s.returnValue, s.err
+				}
+			}
+		},
+	)
+}
+
+// WriteCSV writes the values yielded by g to w as CSV records using
+// `encoding/csv`, writing each of them as soon as it's yielded. If header
+// is not empty, it's written as the first record. It returns after g is
+// done.
+//
+// The values yielded by g can be a `[]string`, a `Record` or a
+// `map[string]string`. A map is written using the order of the columns
+// in header; columns that are not in the map are left empty.
+//
+// If g delivers an error or a value can't be encoded or written, g is
+// stopped with `Generator#Return` and the error is returned.
+func WriteCSV(w io.Writer, g *generator.Generator, header []string) error {
+	var cw *csv.Writer
+	return encode(
+		w, g,
+		func(bw *bufio.Writer, i int, value interface{}) error {
+			if i == 0 {
+				cw = csv.NewWriter(bw)
+				if len(header) > 0 {
+					if err := cw.Write(header); err != nil {
+						return err
+					}
+				}
+			}
+
+			var fields []string
+			switch v := value.(type) {
+			case []string:
+				fields = v
+			case Record:
+				fields = v.Fields
+			case map[string]string:
+				if len(header) == 0 {
+					return fmt.Errorf("codec: can't write map record %d without a header", i)
+				}
+				fields = make([]string, len(header))
+				for j, h := range header {
+					fields[j] = v[h]
+				}
+			default:
+				return fmt.Errorf("codec: can't write %T as CSV record %d", value, i)
+			}
+
+			if err := cw.Write(fields); err != nil {
+				return err
+			}
+			cw.Flush()
+			return cw.Error()
+		},
+		func(bw *bufio.Writer, n int) error {
+			if n == 0 && len(header) > 0 {
+				cw = csv.NewWriter(bw)
+				cw.Write(header)
+				cw.Flush()
+				return cw.Error()
+			}
+			return nil
+		},
+	)
+}
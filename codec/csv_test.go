@@ -0,0 +1,89 @@
+package codec_test
+
+import (
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/generator/codec"
+)
+
+func TestCSV(t *testing.T) {
+	t.Run(`records`, func(t *testing.T) {
+		g := codec.CSV(strings.NewReader("id,name\n1,a\n2,b\n"))
+		values, errs := drain(g)
+		if len(values) != 2 || len(errs) != 0 {
+			t.Fatalf("got: %v, %v. wanted: 2 records, []", values, errs)
+		}
+		r := values[1].(codec.Record)
+		if r.Line != 3 {
+			t.Fatalf("got: %v. wanted: 3", r.Line)
+		}
+		if name, ok := r.Get("name"); name != "b" || !ok {
+			t.Fatalf("got: %v, %v. wanted: b, true", name, ok)
+		}
+		if _, ok := r.Get("missing"); ok {
+			t.Fatalf("got: %v. wanted: false", ok)
+		}
+		want := map[string]string{"id": "2", "name": "b"}
+		if m := r.Map(); !reflect.DeepEqual(m, want) {
+			t.Fatalf("got: %v. wanted: %v", m, want)
+		}
+	})
+	t.Run(`malformed records`, func(t *testing.T) {
+		g := codec.CSV(strings.NewReader("id,name\n1,a\n2\n3,c\"\n4,d\n"))
+		values, errs := drain(g)
+		if len(values) != 2 || len(errs) != 2 {
+			t.Fatalf("got: %v, %v. wanted: 2 records, 2 errors", values, errs)
+		}
+		var parseErr *csv.ParseError
+		if !errors.As(errs[0], &parseErr) || parseErr.Err != csv.ErrFieldCount {
+			t.Fatalf("got: %v. wanted: %v", errs[0], csv.ErrFieldCount)
+		}
+		if !errors.As(errs[1], &parseErr) || parseErr.Err != csv.ErrBareQuote {
+			t.Fatalf("got: %v. wanted: %v", errs[1], csv.ErrBareQuote)
+		}
+		if f, _ := values[1].(codec.Record).Get("name"); f != "d" {
+			t.Fatalf("got: %v. wanted: d", f)
+		}
+	})
+	t.Run(`empty`, func(t *testing.T) {
+		g := codec.CSV(strings.NewReader(""))
+		v, isDone, err := g.Next(nil)
+		if v != nil || !isDone || err != nil {
+			t.Fatalf("got: %v, %v, %v. wanted: <nil>, true, <nil>", v, isDone, err)
+		}
+	})
+}
+
+func TestWriteCSV(t *testing.T) {
+	t.Run(`records`, func(t *testing.T) {
+		var b strings.Builder
+		g := yieldAll(
+			[]string{"1", "a"},
+			codec.Record{Fields: []string{"2", "b,c"}},
+			map[string]string{"name": "d", "id": "3"},
+		)
+		err := codec.WriteCSV(&b, g, []string{"id", "name"})
+		want := "id,name\n1,a\n2,\"b,c\"\n3,d\n"
+		if b.String() != want || err != nil {
+			t.Fatalf("got: %q, %v. wanted: %q, <nil>", b.String(), err, want)
+		}
+	})
+	t.Run(`header only`, func(t *testing.T) {
+		var b strings.Builder
+		err := codec.WriteCSV(&b, yieldAll(), []string{"id", "name"})
+		if want := "id,name\n"; b.String() != want || err != nil {
+			t.Fatalf("got: %q, %v. wanted: %q, <nil>", b.String(), err, want)
+		}
+	})
+	t.Run(`unsupported value`, func(t *testing.T) {
+		var b strings.Builder
+		err := codec.WriteCSV(&b, yieldAll(1), nil)
+		if err == nil {
+			t.Fatalf("got: %v. wanted: an error", err)
+		}
+	})
+}
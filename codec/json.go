@@ -0,0 +1,162 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// JSONArray creates a generator that decodes the elements of the JSON
+// array read from r one element at a time, without reading the whole
+// array into memory.
+//
+// Each element is decoded into the value returned by newElem, which
+// should be a pointer, and the pointer is yielded. If newElem is nil,
+// the elements are decoded into empty interfaces and the decoded values
+// are yielded instead.
+//
+// An element that can't be decoded into the value returned by newElem
+// is delivered as a `*DecodeError`. Malformed JSON can't be recovered
+// from so it stops the generator, returning the syntax error.
+func JSONArray(r io.Reader, newElem func() interface{}) *generator.Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			dec := json.NewDecoder(r)
+
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			if tok != json.Delim('[') {
+				return nil, fmt.Errorf("codec: expected start of JSON array, got %v", tok)
+			}
+
+			newElemFn := newElemOrAny(newElem)
+			for i := 0; dec.More(); i++ {
+				elem := newElemFn()
+
+				var s *stop
+				if err := dec.Decode(elem); err != nil {
+					var typeErr *json.UnmarshalTypeError
+					if !errors.As(err, &typeErr) {
+						return nil, err
+					}
+					s = handle(gc.Error(&DecodeError{Index: i, Err: err}))
+				} else {
+					s = handle(gc.Yield(elemValue(newElem, elem)))
+				}
+				if s != nil {
+					return s.returnValue, s.err
+				}
+			}
+
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		},
+	)
+}
+
+// NDJSON creates a generator that decodes the newline-delimited JSON
+// values read from r one line at a time. Empty lines are skipped.
+//
+// Each value is decoded into the value returned by newElem in the same
+// way as in `JSONArray`. Since every line is a separate JSON value, a
+// malformed line is delivered as a `*DecodeError` and the decoder
+// continues with the next line.
+func NDJSON(r io.Reader, newElem func() interface{}) *generator.Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			br := bufio.NewReader(r)
+			newElemFn := newElemOrAny(newElem)
+
+			for i := 0; ; i++ {
+				// don't use a `bufio.Scanner` here since it can't read
+				// lines that are longer than its maximum token size
+				line, err := br.ReadBytes('\n')
+				if err != nil && err != io.EOF {
+					return nil, err
+				}
+
+				if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+					elem := newElemFn()
+
+					var s *stop
+					if uerr := json.Unmarshal(trimmed, elem); uerr != nil {
+						s = handle(gc.Error(&DecodeError{Index: i, Err: uerr}))
+					} else {
+						s = handle(gc.Yield(elemValue(newElem, elem)))
+					}
+					if s != nil {
+						return s.returnValue, s.err
+					}
+				}
+
+				if err == io.EOF {
+					return nil, nil
+				}
+			}
+		},
+	)
+}
+
+// WriteJSONArray writes the values yielded by g to w as the elements of
+// a JSON array, encoding each of them with `json.Marshal` as soon as it's
+// yielded. It returns after g is done.
+//
+// If g delivers an error or a value can't be encoded or written, g is
+// stopped with `Generator#Return` and the error is returned. The array
+// written to w is left incomplete in that case.
+func WriteJSONArray(w io.Writer, g *generator.Generator) error {
+	return encode(
+		w, g,
+		func(bw *bufio.Writer, i int, value interface{}) error {
+			b, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			if i == 0 {
+				bw.WriteByte('[')
+			} else {
+				bw.WriteByte(',')
+			}
+			_, err = bw.Write(b)
+			return err
+		},
+		func(bw *bufio.Writer, n int) error {
+			// the opening bracket is only written together with the
+			// first element
+			if n == 0 {
+				bw.WriteByte('[')
+			}
+			return bw.WriteByte(']')
+		},
+	)
+}
+
+// WriteNDJSON writes the values yielded by g to w as newline-delimited
+// JSON, encoding each of them with `json.Marshal` as soon as it's
+// yielded. It returns after g is done.
+//
+// If g delivers an error or a value can't be encoded or written, g is
+// stopped with `Generator#Return` and the error is returned.
+func WriteNDJSON(w io.Writer, g *generator.Generator) error {
+	return encode(
+		w, g,
+		func(bw *bufio.Writer, _ int, value interface{}) error {
+			b, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			bw.Write(b)
+			return bw.WriteByte('\n')
+		},
+		nil,
+	)
+}
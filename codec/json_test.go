@@ -0,0 +1,255 @@
+package codec_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/codec"
+)
+
+type item struct {
+	ID int `json:"id"`
+}
+
+func newItem() interface{} {
+	return new(item)
+}
+
+func TestJSONArray(t *testing.T) {
+	t.Run(`typed elements`, func(t *testing.T) {
+		g := codec.JSONArray(strings.NewReader(`[{"id":1},{"id":2},{"id":3}]`), newItem)
+		values, errs := drain(g)
+		want := []interface{}{&item{1}, &item{2}, &item{3}}
+		if !reflect.DeepEqual(values, want) || len(errs) != 0 {
+			t.Fatalf("got: %v, %v. wanted: %v, []", values, errs, want)
+		}
+	})
+	t.Run(`untyped elements`, func(t *testing.T) {
+		g := codec.JSONArray(strings.NewReader(`[1, "a", null]`), nil)
+		values, errs := drain(g)
+		want := []interface{}{1.0, "a", nil}
+		if !reflect.DeepEqual(values, want) || len(errs) != 0 {
+			t.Fatalf("got: %v, %v. wanted: %v, []", values, errs, want)
+		}
+	})
+	t.Run(`mismatched element`, func(t *testing.T) {
+		g := codec.JSONArray(strings.NewReader(`[{"id":1},{"id":"x"},{"id":3}]`), newItem)
+		values, errs := drain(g)
+		want := []interface{}{&item{1}, &item{3}}
+		if !reflect.DeepEqual(values, want) {
+			t.Fatalf("got: %v. wanted: %v", values, want)
+		}
+		var decodeErr *codec.DecodeError
+		if len(errs) != 1 || !errors.As(errs[0], &decodeErr) || decodeErr.Index != 1 {
+			t.Fatalf("got: %v. wanted: a decode error for record 1", errs)
+		}
+	})
+	t.Run(`malformed array`, func(t *testing.T) {
+		g := codec.JSONArray(strings.NewReader(`[{"id":1},{"id":}]`), newItem)
+		g.Next(nil)
+		_, isDone, err := g.Next(nil)
+		var syntaxErr *json.SyntaxError
+		if !isDone || !errors.As(err, &syntaxErr) {
+			t.Fatalf("got: %v, %v. wanted: true, a syntax error", isDone, err)
+		}
+	})
+	t.Run(`not an array`, func(t *testing.T) {
+		g := codec.JSONArray(strings.NewReader(`{"id":1}`), newItem)
+		_, isDone, err := g.Next(nil)
+		if !isDone || err == nil {
+			t.Fatalf("got: %v, %v. wanted: true, an error", isDone, err)
+		}
+	})
+	t.Run(`Return("r")`, func(t *testing.T) {
+		g := codec.JSONArray(strings.NewReader(`[1, 2, 3]`), nil)
+		g.Next(nil)
+		v, isDone, err := g.Return("r")
+		if v != "r" || !isDone || err != nil {
+			t.Fatalf("got: %v, %v, %v. wanted: r, true, <nil>", v, isDone, err)
+		}
+	})
+}
+
+func TestNDJSON(t *testing.T) {
+	t.Run(`lines`, func(t *testing.T) {
+		g := codec.NDJSON(strings.NewReader("{\"id\":1}\n\n{\"id\":2}\n{\"id\":3}"), newItem)
+		values, errs := drain(g)
+		want := []interface{}{&item{1}, &item{2}, &item{3}}
+		if !reflect.DeepEqual(values, want) || len(errs) != 0 {
+			t.Fatalf("got: %v, %v. wanted: %v, []", values, errs, want)
+		}
+	})
+	t.Run(`malformed line`, func(t *testing.T) {
+		g := codec.NDJSON(strings.NewReader("{\"id\":1}\n{\"id\":\n{\"id\":3}\n"), newItem)
+		values, errs := drain(g)
+		want := []interface{}{&item{1}, &item{3}}
+		if !reflect.DeepEqual(values, want) {
+			t.Fatalf("got: %v. wanted: %v", values, want)
+		}
+		var decodeErr *codec.DecodeError
+		if len(errs) != 1 || !errors.As(errs[0], &decodeErr) || decodeErr.Index != 1 {
+			t.Fatalf("got: %v. wanted: a decode error for line 1", errs)
+		}
+	})
+	t.Run(`Error(<e1>)`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		g := codec.NDJSON(strings.NewReader("1\n2\n"), nil)
+		g.Next(nil)
+		v, isDone, err := g.Error(e1)
+		if v != nil || !isDone || err != e1 {
+			t.Fatalf("got: %v, %v, %v. wanted: <nil>, true, e1", v, isDone, err)
+		}
+	})
+}
+
+func TestWriteJSONArray(t *testing.T) {
+	t.Run(`values`, func(t *testing.T) {
+		var b strings.Builder
+		err := codec.WriteJSONArray(&b, yieldAll(1, "a", &item{2}))
+		if want := `[1,"a",{"id":2}]`; b.String() != want || err != nil {
+			t.Fatalf("got: %v, %v. wanted: %v, <nil>", b.String(), err, want)
+		}
+	})
+	t.Run(`no values`, func(t *testing.T) {
+		var b strings.Builder
+		err := codec.WriteJSONArray(&b, yieldAll())
+		if want := `[]`; b.String() != want || err != nil {
+			t.Fatalf("got: %v, %v. wanted: %v, <nil>", b.String(), err, want)
+		}
+	})
+	t.Run(`unsupported value`, func(t *testing.T) {
+		stopped := false
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				gc.Yield(1)
+				_, stopped, _ = gc.Yield(func() {})
+				return nil, nil
+			},
+		)
+		var b strings.Builder
+		err := codec.WriteJSONArray(&b, g)
+		var typeErr *json.UnsupportedTypeError
+		if !errors.As(err, &typeErr) {
+			t.Fatalf("got: %v. wanted: an unsupported type error", err)
+		}
+		if !stopped {
+			t.Fatalf("got: %v. wanted: true", stopped)
+		}
+	})
+	t.Run(`write error`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		stopped := false
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				for i := 0; ; i++ {
+					if _, shouldReturn, _ := gc.Yield(i); shouldReturn {
+						stopped = true
+						return nil, nil
+					}
+				}
+			},
+		)
+		err := codec.WriteJSONArray(errWriter{e1}, g)
+		if err != e1 || !stopped {
+			t.Fatalf("got: %v, %v. wanted: e1, true", err, stopped)
+		}
+	})
+	t.Run(`producer error`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				gc.Yield(1)
+				return nil, e1
+			},
+		)
+		var b strings.Builder
+		if err := codec.WriteJSONArray(&b, g); err != e1 || b.String() != "[1" {
+			t.Fatalf("got: %v, %q. wanted: e1, %q", err, b.String(), "[1")
+		}
+	})
+	t.Run(`error delivered by the producer`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		stopped := false
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				gc.Yield(1)
+				if _, shouldReturn, _ := gc.Error(e1); shouldReturn {
+					stopped = true
+				}
+				return nil, nil
+			},
+		)
+		var b strings.Builder
+		err := codec.WriteJSONArray(&b, g)
+		if err != e1 || b.String() != "[1" || !stopped {
+			t.Fatalf("got: %v, %q, %v. wanted: e1, %q, true", err, b.String(), stopped, "[1")
+		}
+	})
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	var b strings.Builder
+	err := codec.WriteNDJSON(&b, yieldAll(1, "a", &item{2}))
+	if want := "1\n\"a\"\n{\"id\":2}\n"; b.String() != want || err != nil {
+		t.Fatalf("got: %q, %v. wanted: %q, <nil>", b.String(), err, want)
+	}
+
+	e1 := fmt.Errorf("e1")
+	b.Reset()
+	err = codec.WriteNDJSON(&b, generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			gc.Yield(1)
+			return nil, e1
+		},
+	))
+	if want := "1\n"; b.String() != want || err != e1 {
+		t.Fatalf("got: %q, %v. wanted: %q, e1", b.String(), err, want)
+	}
+}
+
+// yieldAll creates a generator that yields each of the values.
+func yieldAll(values ...interface{}) *generator.Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			for _, v := range values {
+				if _, shouldReturn, _ := gc.Yield(v); shouldReturn {
+					break
+				}
+			}
+			return nil, nil
+		},
+	)
+}
+
+type errWriter struct {
+	err error
+}
+
+func (w errWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+// drain calls `Next` until g is done and returns the yielded values and
+// the errors that were received before the last call.
+func drain(g *generator.Generator) ([]interface{}, []error) {
+	var (
+		values []interface{}
+		errs   []error
+	)
+	for {
+		v, isDone, err := g.Next(nil)
+		if isDone {
+			return values, errs
+		}
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			values = append(values, v)
+		}
+	}
+}
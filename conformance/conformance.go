@@ -0,0 +1,328 @@
+// Package conformance checks that an implementation of the generator
+// protocol behaves like this module's goroutine implementation.
+//
+// An implementation is adapted to `Impl`, which creates a generator
+// from a `Func` that only uses the `Controller` interface, and passed to
+// `Run` from a test. `Run` plays every combination of short consumer
+// call sequences and short programs, like a first `Error` or `Return`
+// call, a `Func` that doesn't use its controller and calls after the
+// generator is done, and compares what both sides got with a reference
+// model. `Check` and `Decode` do the same for a single case so that an
+// implementation can also be fuzzed:
+//
+//	func FuzzMyGenerator(f *testing.F) {
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			program, calls := conformance.Decode(data)
+//			conformance.Check(t, myImpl, program, calls)
+//		})
+//	}
+package conformance
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// Generator is the consumer's side of an implementation.
+type Generator interface {
+	Next(value interface{}) (interface{}, bool, error)
+	Return(value interface{}) (interface{}, bool, error)
+	Error(err error) (interface{}, bool, error)
+}
+
+// Controller is the `Func`'s side of an implementation.
+type Controller interface {
+	Yield(value interface{}) (interface{}, bool, error)
+	Error(err error) (interface{}, bool, error)
+}
+
+// Func is the generator function that's passed to an `Impl`.
+type Func func(gc Controller) (interface{}, error)
+
+// Impl creates a generator that runs the `Func`.
+type Impl func(fn Func) Generator
+
+// Goroutine is the `Impl` of `generator.New`.
+func Goroutine(fn Func) Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			return fn(gc)
+		},
+	)
+}
+
+// Op is a controller function invocation of a `Program`. It calls
+// `Controller#Error` with Err if it's not nil, or else
+// `Controller#Yield` with Value.
+type Op struct {
+	Value interface{}
+	Err   error
+
+	// StopOnReturn makes the `Func` return the received value when the
+	// invocation returns shouldReturn equal to true. Otherwise, the
+	// `Func` carries on with the next invocation.
+	StopOnReturn bool
+}
+
+// Program is what the `Func` of a case does: it invokes the controller
+// functions of its operations in order and then returns Value and Err.
+type Program struct {
+	Ops   []Op
+	Value interface{}
+	Err   error
+}
+
+// Call is a generator function call of the consumer. Value is the
+// argument of `Generator#Next` and `Generator#Return`, and Err is the
+// argument of `Generator#Error`.
+type Call struct {
+	Kind  generator.Call
+	Value interface{}
+	Err   error
+}
+
+// Result is what a function returned.
+type Result struct {
+	Value interface{}
+	Done  bool
+	Err   error
+}
+
+// Timeout is how long a call of the consumer may take before it's
+// considered deadlocked.
+var Timeout = 5 * time.Second
+
+// Func returns the `Func` that runs the program. The values returned by
+// the controller functions are appended to received.
+func (p Program) Func(received *[]Result) Func {
+	return func(gc Controller) (interface{}, error) {
+		for _, op := range p.Ops {
+			var r Result
+			if op.Err != nil {
+				r.Value, r.Done, r.Err = gc.Error(op.Err)
+			} else {
+				r.Value, r.Done, r.Err = gc.Yield(op.Value)
+			}
+			*received = append(*received, r)
+			if op.StopOnReturn && r.Done {
+				return r.Value, nil
+			}
+		}
+		return p.Value, p.Err
+	}
+}
+
+// Check makes the calls to a generator of the implementation that runs
+// the program and fails the test if the consumer or the `Func` gets
+// something different from the reference model. If the generator isn't
+// done after the calls, it's stopped with `Generator#Return`.
+func Check(tb testing.TB, impl Impl, program Program, calls []Call) {
+	tb.Helper()
+
+	// stop the generator at the end so that its `Func` returns and all
+	// of the values it received can be compared
+	if results, _ := Expect(program, calls); len(results) == 0 || !results[len(results)-1].Done {
+		calls = append(calls[:len(calls):len(calls)], Call{Kind: generator.CallReturn})
+	}
+	wantResults, wantReceived := Expect(program, calls)
+
+	var received []Result
+	g := impl(program.Func(&received))
+
+	var results []Result
+	for i, c := range calls {
+		r, ok := call(g, c)
+		if !ok {
+			tb.Fatalf("%s: call %d didn't return within %v", Name(program, calls), i, Timeout)
+		}
+		results = append(results, r)
+	}
+
+	if !sameResults(results, wantResults) {
+		tb.Fatalf("%s: the consumer got: %v. wanted: %v",
+			Name(program, calls), formatResults(results), formatResults(wantResults))
+	}
+	if !sameResults(received, wantReceived) {
+		tb.Fatalf("%s: the Func got: %v. wanted: %v",
+			Name(program, calls), formatResults(received), formatResults(wantReceived))
+	}
+}
+
+func call(g Generator, c Call) (Result, bool) {
+	resultChan := make(chan Result, 1)
+	go func() {
+		var r Result
+		switch c.Kind {
+		case generator.CallNext:
+			r.Value, r.Done, r.Err = g.Next(c.Value)
+		case generator.CallReturn:
+			r.Value, r.Done, r.Err = g.Return(c.Value)
+		case generator.CallError:
+			r.Value, r.Done, r.Err = g.Error(c.Err)
+		}
+		resultChan <- r
+	}()
+
+	timer := time.NewTimer(Timeout)
+	defer timer.Stop()
+	select {
+	case r := <-resultChan:
+		return r, true
+	case <-timer.C:
+		return Result{}, false
+	}
+}
+
+// Run checks the implementation with every combination of the consumer
+// call sequences of up to four calls and a set of programs, including
+// one that doesn't use its controller.
+func Run(t *testing.T, impl Impl) {
+	for _, program := range programs() {
+		for _, calls := range callSequences(4) {
+			program, calls := program, calls
+			t.Run(Name(program, calls), func(t *testing.T) {
+				Check(t, impl, program, calls)
+			})
+		}
+	}
+}
+
+func programs() []Program {
+	var (
+		e1 = errors.New("e1")
+		e2 = errors.New("e2")
+	)
+	return []Program{
+		{Value: 0},
+		{Value: 0, Err: e1},
+		{Ops: []Op{{Value: 1}}, Value: 0},
+		{Ops: []Op{{Err: e2}}, Value: 0},
+		{Ops: []Op{{Value: 1}, {Value: 2}, {Value: 3}}, Value: 0},
+		{Ops: []Op{{Value: 1, StopOnReturn: true}, {Err: e2, StopOnReturn: true}}, Value: 0},
+		{Ops: []Op{{Err: e2}, {Value: 2, StopOnReturn: true}}, Value: 0, Err: e1},
+	}
+}
+
+// callSequences returns every sequence of up to n calls.
+func callSequences(n int) [][]Call {
+	sequences := [][]Call{nil}
+	var all [][]Call
+	for length := 1; length <= n; length++ {
+		var next [][]Call
+		for _, seq := range sequences {
+			arg := string(rune('a' + len(seq)))
+			for _, c := range []Call{
+				{Kind: generator.CallNext, Value: arg},
+				{Kind: generator.CallReturn, Value: arg},
+				{Kind: generator.CallError, Err: errors.New(arg)},
+			} {
+				next = append(next, append(append([]Call(nil), seq...), c))
+			}
+		}
+		all = append(all, next...)
+		sequences = next
+	}
+	return all
+}
+
+// Name names the case like the tests of this module, such as
+// `Next("a"),Error(<b>)|Yield(1),Error(<e2>)`.
+func Name(program Program, calls []Call) string {
+	var cs, ops []string
+	for _, c := range calls {
+		switch c.Kind {
+		case generator.CallError:
+			cs = append(cs, fmt.Sprintf("Error(<%v>)", c.Err))
+		case generator.CallNext:
+			cs = append(cs, fmt.Sprintf("Next(%q)", c.Value))
+		case generator.CallReturn:
+			cs = append(cs, fmt.Sprintf("Return(%q)", c.Value))
+		}
+	}
+	for _, op := range program.Ops {
+		name := fmt.Sprintf("Yield(%v)", op.Value)
+		if op.Err != nil {
+			name = fmt.Sprintf("Error(<%v>)", op.Err)
+		}
+		if op.StopOnReturn {
+			name += "!"
+		}
+		ops = append(ops, name)
+	}
+	if len(ops) == 0 {
+		ops = append(ops, "..")
+	}
+	ret := fmt.Sprintf("return(%v)", program.Value)
+	if program.Err != nil {
+		ret = fmt.Sprintf("return(%v,<%v>)", program.Value, program.Err)
+	}
+	return strings.Join(cs, ",") + "|" + strings.Join(ops, ",") + "," + ret
+}
+
+func sameResults(a, b []Result) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func formatResults(results []Result) string {
+	var s []string
+	for _, r := range results {
+		s = append(s, fmt.Sprintf("(%v, %v, %v)", r.Value, r.Done, r.Err))
+	}
+	return "[" + strings.Join(s, " ") + "]"
+}
+
+// Decode turns arbitrary bytes, like the input of a fuzz target, into a
+// program of up to four operations and up to eight calls.
+func Decode(data []byte) (Program, []Call) {
+	next := func() byte {
+		if len(data) == 0 {
+			return 0
+		}
+		b := data[0]
+		data = data[1:]
+		return b
+	}
+
+	var program Program
+	header := next()
+	for i := 0; i < int(header%5); i++ {
+		b := next()
+		op := Op{Value: i + 1, StopOnReturn: b&2 != 0}
+		if b&1 != 0 {
+			op = Op{Err: fmt.Errorf("e%d", i+1), StopOnReturn: b&2 != 0}
+		}
+		program.Ops = append(program.Ops, op)
+	}
+	program.Value = 0
+	if header&0x80 != 0 {
+		program.Err = errors.New("e0")
+	}
+
+	var calls []Call
+	for len(data) > 0 && len(calls) < 8 {
+		arg := string(rune('a' + len(calls)))
+		switch next() % 3 {
+		case 0:
+			calls = append(calls, Call{Kind: generator.CallNext, Value: arg})
+		case 1:
+			calls = append(calls, Call{Kind: generator.CallReturn, Value: arg})
+		case 2:
+			calls = append(calls, Call{Kind: generator.CallError, Err: errors.New(arg)})
+		}
+	}
+	return program, calls
+}
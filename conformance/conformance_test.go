@@ -0,0 +1,72 @@
+package conformance_test
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/conformance"
+)
+
+func TestGoroutine(t *testing.T) {
+	conformance.Run(t, conformance.Goroutine)
+}
+
+func FuzzGoroutine(f *testing.F) {
+	f.Add([]byte{0x00, 0x00})
+	f.Add([]byte{0x81, 0x01, 0x02, 0x01})
+	f.Add([]byte{0x03, 0x00, 0x03, 0x01, 0x02, 0x00, 0x00, 0x01})
+	f.Add([]byte{0x04, 0x01, 0x00, 0x02, 0x03, 0x02, 0x01, 0x00, 0x00})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		program, calls := conformance.Decode(data)
+		conformance.Check(t, conformance.Goroutine, program, calls)
+	})
+}
+
+// returnAsNext is a broken implementation whose `Return` acts like
+// `Next`.
+type returnAsNext struct {
+	conformance.Generator
+}
+
+func (g returnAsNext) Return(value interface{}) (interface{}, bool, error) {
+	return g.Next(value)
+}
+
+func TestCheck(t *testing.T) {
+	program := conformance.Program{Ops: []conformance.Op{{Value: 1}, {Value: 2}}, Value: 0}
+	calls := []conformance.Call{
+		{Kind: generator.CallNext, Value: "a"},
+		{Kind: generator.CallReturn, Value: "b"},
+	}
+	broken := func(fn conformance.Func) conformance.Generator {
+		return returnAsNext{conformance.Goroutine(fn)}
+	}
+
+	tb := &fakeTB{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conformance.Check(tb, broken, program, calls)
+	}()
+	<-done
+
+	want := `Next("a"),Return("b")|Yield(1),Yield(2),return(0): the consumer got: [(1, false, <nil>) (2, false, <nil>)]. wanted: [(1, false, <nil>) (0, true, <nil>)]`
+	if tb.msg != want {
+		t.Fatalf("got: %v. wanted: %v", tb.msg, want)
+	}
+}
+
+// fakeTB records the message of `Fatalf`.
+type fakeTB struct {
+	testing.TB
+	msg string
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Fatalf(format string, args ...interface{}) {
+	tb.msg = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
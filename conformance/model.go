@@ -0,0 +1,138 @@
+package conformance
+
+import "github.com/bmdelacruz/generator"
+
+// model is the reference model of the protocol. It plays the program
+// the way the goroutine implementation does, one consumer call at a
+// time, without running the `Func`.
+type model struct {
+	program Program
+
+	isDone  bool
+	started bool
+	wasUsed bool
+
+	// first is the first call of the consumer if it wasn't a `Next`.
+	// the first controller function invocation consumes it.
+	first         *Call
+	firstConsumed bool
+
+	// throwFirst is the error of a first `Error` call that will be
+	// received by the waiting controller function, whatever the next
+	// call of the consumer is.
+	throwFirst error
+
+	pc       int
+	received []Result
+}
+
+// Expect returns what the consumer should get from the calls and what
+// the controller functions of the program should return to the `Func`,
+// according to the reference model.
+func Expect(program Program, calls []Call) (results []Result, received []Result) {
+	m := &model{program: program}
+	for _, c := range calls {
+		results = append(results, m.call(c))
+	}
+	return results, m.received
+}
+
+func (m *model) call(c Call) Result {
+	if m.isDone {
+		return Result{Done: true}
+	}
+
+	if !m.started {
+		m.started = true
+		switch c.Kind {
+		case generator.CallReturn:
+			m.isDone = true
+			m.first = &c
+		case generator.CallError:
+			m.first = &c
+		}
+		return m.run()
+	}
+
+	// the `Func` is waiting in a controller function
+	var r Result
+	switch c.Kind {
+	case generator.CallNext:
+		r = Result{Value: c.Value}
+	case generator.CallReturn:
+		m.isDone = true
+		r = Result{Value: c.Value, Done: true}
+	case generator.CallError:
+		r = Result{Err: c.Err}
+	}
+	if m.throwFirst != nil {
+		r, m.throwFirst = Result{Err: m.throwFirst}, nil
+	}
+	if ret, ok := m.receive(r); ok {
+		return ret
+	}
+	return m.run()
+}
+
+// run runs the program until it waits for the consumer or returns. It
+// returns what the waiting consumer call returns.
+func (m *model) run() Result {
+	for m.pc < len(m.program.Ops) {
+		op := m.program.Ops[m.pc]
+		m.wasUsed = true
+
+		if m.first != nil && !m.firstConsumed {
+			m.firstConsumed = true
+			if m.first.Kind == generator.CallReturn {
+				if ret, ok := m.receive(Result{Value: m.first.Value, Done: true}); ok {
+					return ret
+				}
+				continue
+			}
+			// the consumer's `Error` gets an empty result and the error
+			// is thrown in when the consumer calls again
+			m.throwFirst = m.first.Err
+			return Result{}
+		}
+		m.firstConsumed = true
+
+		if m.isDone {
+			if ret, ok := m.receive(Result{Done: true}); ok {
+				return ret
+			}
+			continue
+		}
+		if op.Err != nil {
+			return Result{Err: op.Err}
+		}
+		return Result{Value: op.Value}
+	}
+	return m.finish(m.program.Value, m.program.Err)
+}
+
+// receive records what the current controller function returned to the
+// `Func` and moves to the next operation. It returns the result of the
+// `Func` and true if the `Func` returns because of it.
+func (m *model) receive(r Result) (Result, bool) {
+	m.received = append(m.received, r)
+	op := m.program.Ops[m.pc]
+	m.pc++
+	if op.StopOnReturn && r.Done {
+		return m.finish(r.Value, nil), true
+	}
+	return Result{}, false
+}
+
+func (m *model) finish(value interface{}, err error) Result {
+	if !m.wasUsed && m.first != nil {
+		switch m.first.Kind {
+		case generator.CallReturn:
+			value = m.first.Value
+		case generator.CallError:
+			err = m.first.Err
+		}
+	}
+	m.isDone = true
+	m.pc = len(m.program.Ops)
+	return Result{Value: value, Done: true, Err: err}
+}
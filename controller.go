@@ -1,13 +1,35 @@
 package generator
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrYieldTimeout is returned by `Controller#YieldTimeout` when the
+	// consumer hasn't called any of the generator functions in time.
+	ErrYieldTimeout = errors.New("generator: yield timed out")
+
+	// ErrYieldCanceled is returned by `Controller#YieldContext`, along
+	// with the context's error, when the context is done before the
+	// consumer calls any of the generator functions.
+	ErrYieldCanceled = errors.New("generator: yield canceled")
+)
+
 // Controller provides functions that will control the generator
 // associated with its instance.
 type Controller struct {
-	g *Generator
+	g *state
 
 	// wasUsed is equal to true if any of the functions of this
 	// controller was used
 	wasUsed bool
+
+	// abandoned is equal to true if the `Func` has stopped waiting for
+	// the consumer in `YieldTimeout` or `YieldContext`
+	abandoned bool
 }
 
 // Yield sends the value to the consumer of the generator and then
@@ -19,15 +41,85 @@ type Controller struct {
 //
 // Returns ([value], [shouldReturn], [error])
 func (c *Controller) Yield(value interface{}) (interface{}, bool, error) {
+	c.g.trace(TraceControllerYield, value, nil)
+	if c.g.metrics != nil {
+		c.g.metrics.Yielded(c.g.id)
+	}
 	return c.sendAndReceive(
 		&status{
 			value: value,
 			done:  false,
 			err:   nil,
 		},
+		c.g.callerOf(),
+		nil,
+	)
+}
+
+// YieldTimeout is like `Yield` but it stops waiting for the consumer if
+// it hasn't called any of the generator functions within the duration
+// after it received the value. In that case, it returns
+// (<nil>, false, `ErrYieldTimeout`).
+//
+// Once the `Func` stops waiting, the conversation with the consumer is
+// over: the succeeding controller function invocations will return
+// (<nil>, true, <nil>) without waiting, and the next generator function
+// call of the consumer will wait for the `Func` to return and return
+// its return values with isDone equal to true, ignoring the argument of
+// the call. The `Func` should release what it holds and return.
+//
+// Returns ([value], [shouldReturn], [error])
+func (c *Controller) YieldTimeout(value interface{}, d time.Duration) (interface{}, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return c.yieldUntil(ctx, value, c.g.callerOf(), func(error) error {
+		return ErrYieldTimeout
+	})
+}
+
+// YieldContext is like `YieldTimeout` but it stops waiting for the
+// consumer when ctx is done. In that case, it returns an error that
+// wraps both `ErrYieldCanceled` and the context's error.
+//
+// Returns ([value], [shouldReturn], [error])
+func (c *Controller) YieldContext(ctx context.Context, value interface{}) (interface{}, bool, error) {
+	return c.yieldUntil(ctx, value, c.g.callerOf(), func(err error) error {
+		return fmt.Errorf("%w: %w", ErrYieldCanceled, err)
+	})
+}
+
+// yieldUntil yields the value and stops waiting for the consumer when
+// ctx is done. wrapErr turns the context's error into the error that's
+// returned to the `Func`.
+func (c *Controller) yieldUntil(
+	ctx context.Context,
+	value interface{},
+	caller string,
+	wrapErr func(error) error,
+) (interface{}, bool, error) {
+	c.g.trace(TraceControllerYield, value, nil)
+	if c.g.metrics != nil {
+		c.g.metrics.Yielded(c.g.id)
+	}
+	value, shouldReturn, err := c.sendAndReceive(
+		&status{
+			value: value,
+			done:  false,
+			err:   nil,
+		},
+		caller,
+		ctx.Done(),
 	)
+	if err == errAbandoned {
+		err = wrapErr(ctx.Err())
+	}
+	return value, shouldReturn, err
 }
 
+// errAbandoned is returned by `exchange` when it stops waiting for the
+// consumer.
+var errAbandoned = errors.New("generator: abandoned")
+
 // Error sends an error to the consumer of the generator and then
 // waits for the next generator function invocation that will get
 // the data that will be returned by this function.
@@ -37,20 +129,47 @@ func (c *Controller) Yield(value interface{}) (interface{}, bool, error) {
 //
 // Returns ([value], [shouldReturn], [error])
 func (c *Controller) Error(err error) (interface{}, bool, error) {
+	c.g.trace(TraceControllerError, nil, err)
+	if c.g.metrics != nil {
+		c.g.metrics.Errored(c.g.id)
+	}
 	return c.sendAndReceive(
 		&status{
 			value: nil,
 			done:  false,
 			err:   err,
 		},
+		c.g.callerOf(),
+		nil,
 	)
 }
 
-func (c *Controller) sendAndReceive(statusToSend *status) (interface{}, bool, error) {
+// sendAndReceive sends the status to the consumer. caller is the
+// location of the controller function call, which is only known when
+// the generator is in the registry. It stops waiting for the consumer
+// when abandon is closed.
+func (c *Controller) sendAndReceive(
+	statusToSend *status,
+	caller string,
+	abandon <-chan struct{},
+) (interface{}, bool, error) {
+	value, shouldReturn, err := c.exchange(statusToSend, caller, abandon)
+	c.g.trace(TraceSend, value, err)
+	return value, shouldReturn, err
+}
+
+func (c *Controller) exchange(
+	statusToSend *status,
+	caller string,
+	abandon <-chan struct{},
+) (interface{}, bool, error) {
 	if !c.wasUsed {
 		// mark that any of the controller function has been used
 		c.wasUsed = true
 	}
+	if c.abandoned {
+		return nil, true, nil
+	}
 
 	select {
 	// if there is a saved error or return value earlier, receive it
@@ -71,13 +190,15 @@ func (c *Controller) sendAndReceive(statusToSend *status) (interface{}, bool, er
 				// the generator controller function needs to be overridden
 				// since there is a pending error that was sent by the consumer
 				// of the generator
+				c.g.setState(StateSuspended, caller)
 				c.g.statusChan <- &status{
 					value: nil,
 					done:  false,
 					err:   nil,
 				}
-				<-c.g.retStatusChan
-				<-c.g.isDoneChan
+				if _, ok := c.receive(abandon); !ok {
+					return nil, false, errAbandoned
+				}
 				return nil, false, err
 			}
 		}
@@ -88,8 +209,30 @@ func (c *Controller) sendAndReceive(statusToSend *status) (interface{}, bool, er
 		return nil, true, nil
 	}
 
+	if c.g.metrics != nil {
+		defer c.g.observeProducerWait(time.Now())
+	}
+	c.g.setState(StateSuspended, caller)
 	c.g.statusChan <- statusToSend
-	rs := <-c.g.retStatusChan
-	<-c.g.isDoneChan
+	rs, ok := c.receive(abandon)
+	if !ok {
+		return nil, false, errAbandoned
+	}
 	return rs.Data()
 }
+
+// receive waits for the next generator function call of the consumer.
+// If abandon is closed first, it stops waiting for good and returns
+// false.
+func (c *Controller) receive(abandon <-chan struct{}) (retStatus, bool) {
+	defer c.g.setState(StateRunning, "")
+
+	select {
+	case rs := <-c.g.retStatusChan:
+		<-c.g.isDoneChan
+		return rs, true
+	case <-abandon:
+		c.abandoned = true
+		return nil, false
+	}
+}
@@ -0,0 +1,81 @@
+// Package fswalk provides a generator that walks a file system tree.
+//
+// Unlike `fs.WalkDir`, the consumer of the generator drives the walk.
+// It receives the visited entries one at a time and tells the walker
+// what to do next through the argument of `Generator#Next`.
+package fswalk
+
+import (
+	"io/fs"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// Entry is the value yielded by the generator for every file or
+// directory that was visited.
+type Entry struct {
+	fs.DirEntry
+
+	// Path is the path of the entry which contains the root that was
+	// passed to `Walk` as a prefix.
+	Path string
+}
+
+// Walk creates a generator that walks the file tree rooted at root,
+// yielding an `Entry` for each file or directory in the tree, including
+// root. The files are walked in lexical order like in `fs.WalkDir`.
+//
+// The value passed to `Generator#Next` after receiving an entry decides
+// what the walker will do next:
+//
+//   - `nil` (or any value that is not listed below) continues the walk.
+//   - `fs.SkipDir` skips the directory that was just received. If the
+//     entry is not a directory, the remaining files in its parent
+//     directory are skipped.
+//   - `fs.SkipAll` stops the walk.
+//
+// Errors that are encountered while walking, such as a directory that
+// can't be read, are delivered to the consumer as `Generator#Next`'s
+// error without stopping the walk. The value passed to the succeeding
+// `Generator#Next` is handled in the same way as above.
+//
+// Calling `Generator#Return` stops the walk; the `Func` will return the
+// value passed to it. Calling `Generator#Error` also stops the walk; the
+// `Func` will return the error passed to it.
+func Walk(fsys fs.FS, root string) *generator.Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			var (
+				returnValue interface{}
+				thrownErr   error
+			)
+
+			err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+				var (
+					value        interface{}
+					shouldReturn bool
+				)
+				if err != nil {
+					value, shouldReturn, thrownErr = gc.Error(err)
+				} else {
+					value, shouldReturn, thrownErr = gc.Yield(Entry{DirEntry: d, Path: path})
+				}
+
+				switch {
+				case shouldReturn:
+					returnValue = value
+					return fs.SkipAll
+				case thrownErr != nil:
+					return thrownErr
+				case value == fs.SkipDir:
+					return fs.SkipDir
+				case value == fs.SkipAll:
+					return fs.SkipAll
+				}
+				return nil
+			})
+
+			return returnValue, err
+		},
+	)
+}
@@ -0,0 +1,133 @@
+package fswalk_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/fswalk"
+)
+
+var testFS = fstest.MapFS{
+	"a/1.txt":   {},
+	"a/2.txt":   {},
+	"b/c/3.txt": {},
+	"b/4.txt":   {},
+	"5.txt":     {},
+}
+
+func TestWalk(t *testing.T) {
+	t.Run(`Next(nil)..`, func(t *testing.T) {
+		g := fswalk.Walk(testFS, ".")
+		got := collect(t, g, func(fswalk.Entry) interface{} { return nil })
+		want := []string{
+			".", "5.txt", "a", "a/1.txt", "a/2.txt",
+			"b", "b/4.txt", "b/c", "b/c/3.txt",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got: %v. wanted: %v", got, want)
+		}
+	})
+	t.Run(`Next(fs.SkipDir) on directory`, func(t *testing.T) {
+		g := fswalk.Walk(testFS, ".")
+		got := collect(t, g, func(e fswalk.Entry) interface{} {
+			if e.Path == "a" {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		want := []string{".", "5.txt", "a", "b", "b/4.txt", "b/c", "b/c/3.txt"}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got: %v. wanted: %v", got, want)
+		}
+	})
+	t.Run(`Next(fs.SkipDir) on file`, func(t *testing.T) {
+		g := fswalk.Walk(testFS, ".")
+		got := collect(t, g, func(e fswalk.Entry) interface{} {
+			if e.Path == "b/4.txt" {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		want := []string{".", "5.txt", "a", "a/1.txt", "a/2.txt", "b", "b/4.txt"}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got: %v. wanted: %v", got, want)
+		}
+	})
+	t.Run(`Next(fs.SkipAll)`, func(t *testing.T) {
+		g := fswalk.Walk(testFS, ".")
+		got := collect(t, g, func(e fswalk.Entry) interface{} {
+			if e.Path == "a/1.txt" {
+				return fs.SkipAll
+			}
+			return nil
+		})
+		want := []string{".", "5.txt", "a", "a/1.txt"}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got: %v. wanted: %v", got, want)
+		}
+	})
+	t.Run(`Return("r")`, func(t *testing.T) {
+		g := fswalk.Walk(testFS, "a")
+		v, isDone, err := g.Next(nil)
+		if isDone || err != nil || v.(fswalk.Entry).Path != "a" {
+			t.Fatalf("got: %v, %v, %v. wanted: a, false, <nil>", v, isDone, err)
+		}
+		v, isDone, err = g.Return("r")
+		if v != "r" || !isDone || err != nil {
+			t.Fatalf("got: %v, %v, %v. wanted: r, true, <nil>", v, isDone, err)
+		}
+		v, isDone, err = g.Next(nil)
+		if v != nil || !isDone || err != nil {
+			t.Fatalf("got: %v, %v, %v. wanted: <nil>, true, <nil>", v, isDone, err)
+		}
+	})
+	t.Run(`Error(<e1>)`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		g := fswalk.Walk(testFS, "a")
+		g.Next(nil)
+		v, isDone, err := g.Error(e1)
+		if v != nil || !isDone || err != e1 {
+			t.Fatalf("got: %v, %v, %v. wanted: <nil>, true, e1", v, isDone, err)
+		}
+	})
+	t.Run(`missing root`, func(t *testing.T) {
+		g := fswalk.Walk(testFS, "missing")
+		_, isDone, err := g.Next(nil)
+		if isDone || !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("got: %v, %v. wanted: false, %v", isDone, err, fs.ErrNotExist)
+		}
+		v, isDone, err := g.Next(nil)
+		if v != nil || !isDone || err != nil {
+			t.Fatalf("got: %v, %v, %v. wanted: <nil>, true, <nil>", v, isDone, err)
+		}
+	})
+}
+
+// collect walks g until it's done and returns the paths of the entries
+// that were received. The value returned by fn is sent to the walker
+// after receiving each entry.
+func collect(t *testing.T, g *generator.Generator, fn func(fswalk.Entry) interface{}) []string {
+	t.Helper()
+
+	var (
+		paths []string
+		send  interface{}
+	)
+	for {
+		v, isDone, err := g.Next(send)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if isDone {
+			return paths
+		}
+		e := v.(fswalk.Entry)
+		paths = append(paths, e.Path)
+		send = fn(e)
+	}
+}
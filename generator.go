@@ -1,8 +1,42 @@
 package generator
 
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// lastID is the ID of the last generator that was created
+var lastID uint64
+
 // Generator provides functions that will send and receive data to and
 // from the `Func` associated with it.
 type Generator struct {
+	// the state is kept apart from the generator so that the goroutine
+	// of the `Func` doesn't keep the generator reachable. this lets the
+	// finalizer of the generator find out that it has been abandoned.
+	*state
+}
+
+type state struct {
+	id       uint64
+	name     string
+	labels   []string
+	tracer   Tracer
+	metrics  Metrics
+	watchdog *Watchdog
+	recorder *Recorder
+
+	// debug is only set when the generator is in the registry
+	debug *debugInfo
+
+	// terminal is set once to `terminalFinished` when the `Func` has
+	// returned or to `terminalLeaked` when the generator was garbage
+	// collected before that, whichever comes first. unlike `isDone`,
+	// it's accessed atomically since the finalizer of the generator
+	// runs in its own goroutine.
+	terminal int32
+
 	isDone bool
 
 	// isDoneChan is for preventing data race conditions. it is safe to
@@ -11,21 +45,52 @@ type Generator struct {
 	statusChan    chan *status
 	retStatusChan chan retStatus
 	firstCallChan chan firstCall
+
+	// abandonedChan receives the last status when the `Func` returns
+	// after it stopped waiting for the consumer in `YieldTimeout` or
+	// `YieldContext`. the next generator function call receives it
+	// instead of handing its status over to the `Func`.
+	abandonedChan chan *status
 }
 
+// the values of `state.terminal`
+const (
+	terminalNone int32 = iota
+	terminalFinished
+	terminalLeaked
+)
+
 // Func is the signature of the generator function
 type Func func(controller *Controller) (interface{}, error)
 
 // New creates an instance of a generator and spawns a goroutine where
-// the generator function will run.
-func New(generatorFunc Func) *Generator {
-	generator := &Generator{
-		isDone: false,
+// the generator function will run. The options configure the generator
+// before the goroutine is spawned.
+func New(generatorFunc Func, options ...Option) *Generator {
+	generator := &Generator{&state{
+		id:      atomic.AddUint64(&lastID, 1),
+		metrics: defaultMetrics(),
+		isDone:  false,
 
 		isDoneChan:    make(chan struct{}),
 		statusChan:    make(chan *status),
 		retStatusChan: make(chan retStatus),
 		firstCallChan: make(chan firstCall, 1),
+		abandonedChan: make(chan *status, 1),
+	}}
+	for _, option := range options {
+		option(generator)
+	}
+
+	generator.register(1)
+	if generator.metrics != nil {
+		generator.metrics.Created(generator.id)
+	}
+	if generator.metrics != nil || generator.debug != nil {
+		runtime.SetFinalizer(generator, (*Generator).finalize)
+	}
+	if generator.watchdog != nil {
+		generator.watchdog.watch(generator.state)
 	}
 
 	go generator.start(generatorFunc)
@@ -33,6 +98,12 @@ func New(generatorFunc Func) *Generator {
 	return generator
 }
 
+// ID returns the identifier of the generator, which is unique among the
+// generators created by the process.
+func (g *Generator) ID() uint64 {
+	return g.id
+}
+
 // Next provides the value that should be returned by the current
 // generator controller function and retrieves the next yielded
 // value from the `Func`. The argument is ignored when `Next` is
@@ -40,10 +111,27 @@ func New(generatorFunc Func) *Generator {
 //
 // Returns ([value], [isDone], [error])
 func (g *Generator) Next(value interface{}) (interface{}, bool, error) {
+	v, isDone, err := g.next(value)
+	if g.recorder != nil {
+		g.recorder.record(CallNext, value, nil, v, isDone, err)
+	}
+	return v, isDone, err
+}
+
+func (g *state) next(value interface{}) (interface{}, bool, error) {
+	g.trace(TraceGeneratorNext, value, nil)
 	if g.isDone {
 		return nil, true, nil
 	}
-	g.retStatusChan <- &yieldRetStatus{value}
+	if g.metrics != nil {
+		defer g.observeConsumerWait(time.Now())
+	}
+	if g.watchdog != nil {
+		defer g.consumerWaits()()
+	}
+	if last := g.handOver(&yieldRetStatus{value}); last != nil {
+		return last.Data()
+	}
 	g.isDoneChan <- struct{}{}
 	return (<-g.statusChan).Data()
 }
@@ -60,10 +148,27 @@ func (g *Generator) Next(value interface{}) (interface{}, bool, error) {
 //
 // Returns ([value], [isDone], [error])
 func (g *Generator) Return(value interface{}) (interface{}, bool, error) {
+	v, isDone, err := g.returnValue(value)
+	if g.recorder != nil {
+		g.recorder.record(CallReturn, value, nil, v, isDone, err)
+	}
+	return v, isDone, err
+}
+
+func (g *state) returnValue(value interface{}) (interface{}, bool, error) {
+	g.trace(TraceGeneratorReturn, value, nil)
 	if g.isDone {
 		return nil, true, nil
 	}
-	g.retStatusChan <- &returnRetStatus{value}
+	if g.metrics != nil {
+		defer g.observeConsumerWait(time.Now())
+	}
+	if g.watchdog != nil {
+		defer g.consumerWaits()()
+	}
+	if last := g.handOver(&returnRetStatus{value}); last != nil {
+		return last.Data()
+	}
 	g.isDone = true
 	g.isDoneChan <- struct{}{}
 	return (<-g.statusChan).Data()
@@ -79,15 +184,36 @@ func (g *Generator) Return(value interface{}) (interface{}, bool, error) {
 //
 // Returns ([value], [isDone], [error])
 func (g *Generator) Error(err error) (interface{}, bool, error) {
+	v, isDone, retErr := g.throwError(err)
+	if g.recorder != nil {
+		g.recorder.record(CallError, nil, err, v, isDone, retErr)
+	}
+	return v, isDone, retErr
+}
+
+func (g *state) throwError(err error) (interface{}, bool, error) {
+	g.trace(TraceGeneratorError, nil, err)
 	if g.isDone {
 		return nil, true, nil
 	}
-	g.retStatusChan <- &errorRetStatus{err}
+	if g.metrics != nil {
+		defer g.observeConsumerWait(time.Now())
+	}
+	if g.watchdog != nil {
+		defer g.consumerWaits()()
+	}
+	if last := g.handOver(&errorRetStatus{err}); last != nil {
+		return last.Data()
+	}
 	g.isDoneChan <- struct{}{}
 	return (<-g.statusChan).Data()
 }
 
-func (g *Generator) start(generatorFunc Func) {
+func (g *state) start(generatorFunc Func) {
+	g.setLabels()
+	if g.watchdog != nil {
+		g.watchdog.started(g.id)
+	}
 	controller := &Controller{g: g}
 
 	// receive the initial data sent from any of the generator functions
@@ -113,7 +239,19 @@ func (g *Generator) start(generatorFunc Func) {
 	// `isDone` may have already been updated so it's safe to access
 	// (to prevent data race)
 	<-g.isDoneChan
+	g.setState(StateRunning, "")
+
+	if g.tracer != nil {
+		// report the panic before it crashes the program
+		defer func() {
+			if r := recover(); r != nil {
+				g.trace(TracePanic, r, nil)
+				panic(r)
+			}
+		}()
+	}
 
+	g.trace(TraceStart, nil, nil)
 	value, err := generatorFunc(controller)
 
 	// this condition will be equal to true when any of the generator
@@ -133,6 +271,36 @@ func (g *Generator) start(generatorFunc Func) {
 		}
 	}
 
+	g.trace(TraceDone, value, err)
+	// a generator that was abandoned while its `Func` was running, like
+	// in `Controller#YieldTimeout`, has already been counted as leaked
+	finished := atomic.CompareAndSwapInt32(&g.terminal, terminalNone, terminalFinished)
+	if g.metrics != nil {
+		if finished {
+			g.metrics.Finished(g.id)
+		}
+		if err != nil {
+			g.metrics.Errored(g.id)
+		}
+	}
+	if g.debug != nil {
+		g.unregister()
+	}
+	if g.watchdog != nil {
+		g.watchdog.unwatch(g.id)
+	}
+
+	if controller.abandoned {
+		// the consumer isn't waiting for the `Func`. its next call will
+		// receive the last status and mark the generator as done.
+		g.abandonedChan <- &status{
+			value: value,
+			done:  true,
+			err:   err,
+		}
+		return
+	}
+
 	// don't forget to mark the generator as done. return may not have
 	// been called.
 	//
@@ -150,3 +318,17 @@ func (g *Generator) start(generatorFunc Func) {
 		err:   err,
 	}
 }
+
+// handOver sends the status from a generator function to the `Func`.
+// If the `Func` has stopped waiting for the consumer, it waits for the
+// `Func` to return, marks the generator as done and returns the last
+// status instead.
+func (g *state) handOver(rs retStatus) *status {
+	select {
+	case g.retStatusChan <- rs:
+		return nil
+	case last := <-g.abandonedChan:
+		g.isDone = true
+		return last
+	}
+}
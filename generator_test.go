@@ -1,8 +1,11 @@
 package generator_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/bmdelacruz/generator"
 )
@@ -156,6 +159,85 @@ func TestController_Error(t *testing.T) {
 	})
 }
 
+func TestController_YieldTimeout(t *testing.T) {
+	t.Run(`Next("a"),Next("b")|YieldTimeout(1)`, func(t *testing.T) {
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				testWith(t).pexpect(gc.YieldTimeout(1, time.Minute)).toReturn("b", false, nil)
+				return 0, nil
+			},
+		)
+		testWith(t).expect(g.Next("a")).toReturn(1, false, nil)
+		testWith(t).expect(g.Next("b")).toReturn(0, true, nil)
+	})
+	t.Run(`Next("a"),<timeout>,Next("b"),Next("c")|YieldTimeout(1),Yield(2)`, func(t *testing.T) {
+		abandoned := make(chan struct{})
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				testWith(t).pexpect(gc.YieldTimeout(1, time.Millisecond)).toReturn(nil, false, generator.ErrYieldTimeout)
+				testWith(t).pexpect(gc.Yield(2)).toReturn(nil, true, nil)
+				close(abandoned)
+				return 0, nil
+			},
+		)
+		testWith(t).expect(g.Next("a")).toReturn(1, false, nil)
+		<-abandoned
+		testWith(t).expect(g.Next("b")).toReturn(0, true, nil)
+		testWith(t).expect(g.Next("c")).toReturn(nil, true, nil)
+	})
+	t.Run(`Next("a"),Return("b")|YieldTimeout(1)`, func(t *testing.T) {
+		abandoned := make(chan struct{})
+		release := make(chan struct{})
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				testWith(t).pexpect(gc.YieldTimeout(1, time.Millisecond)).toReturn(nil, false, generator.ErrYieldTimeout)
+				close(abandoned)
+				<-release
+				return 0, nil
+			},
+		)
+		testWith(t).expect(g.Next("a")).toReturn(1, false, nil)
+		<-abandoned
+
+		// the consumer's call waits for the abandoned `Func` to return
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			close(release)
+		}()
+		testWith(t).expect(g.Return("b")).toReturn(0, true, nil)
+		testWith(t).expect(g.Error(fmt.Errorf("e1"))).toReturn(nil, true, nil)
+	})
+}
+
+func TestController_YieldContext(t *testing.T) {
+	t.Run(`Next("a"),<cancel>,Next("b")|YieldContext(1)`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				_, shouldReturn, err := gc.YieldContext(ctx, 1)
+				if shouldReturn || !errors.Is(err, generator.ErrYieldCanceled) || !errors.Is(err, context.Canceled) {
+					return nil, fmt.Errorf("got: %v, %v. wanted: false, a canceled yield", shouldReturn, err)
+				}
+				return 0, nil
+			},
+		)
+		testWith(t).expect(g.Next("a")).toReturn(1, false, nil)
+		cancel()
+		testWith(t).expect(g.Next("b")).toReturn(0, true, nil)
+	})
+	t.Run(`Error(<e1>),Next("b")|YieldContext(1)`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				testWith(t).pexpect(gc.YieldContext(context.Background(), 1)).toReturn(nil, false, e1)
+				return 0, nil
+			},
+		)
+		testWith(t).expect(g.Error(e1)).toReturn(nil, false, nil)
+		testWith(t).expect(g.Next("b")).toReturn(0, true, nil)
+	})
+}
+
 // utility stuff =====================================================
 
 type tw struct {
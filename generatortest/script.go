@@ -0,0 +1,257 @@
+// Package generatortest provides a fluent way to test the `Func` of a
+// generator by scripting the consumer's side of the conversation:
+//
+//	generatortest.New().
+//		ExpectYield(1).
+//		Send("a").ExpectYield(2).
+//		ThrowIn(errBad).ExpectError(errRejected).
+//		Stop("r").ExpectReturn("r").
+//		Run(t, fn)
+//
+// A script only records the steps. `Script#Run` creates a generator for
+// the `Func` and plays the steps against it, so the same script can be
+// run against several `Func`s and scripts can be kept in tables. The
+// methods that add a step return a new script and leave the original
+// one unchanged.
+//
+// The calls of the consumer (`Send`, `ThrowIn` and `Stop`) are made in
+// order and the expectations check the result of the last call. An
+// expectation that isn't preceded by a call calls `Generator#Next` with
+// a nil value first. Each call fails the test if it doesn't return
+// within the timeout of the script, which catches `Func`s that deadlock.
+package generatortest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// DefaultTimeout is how long a call of the consumer may take before the
+// test fails, unless it's changed with `Script#Timeout`.
+const DefaultTimeout = 5 * time.Second
+
+// Script scripts the consumer of a generator. The zero value is an
+// empty script that uses the `DefaultTimeout`.
+type Script struct {
+	steps   []step
+	timeout time.Duration
+}
+
+// step is either a call of the consumer or an expectation.
+type step struct {
+	name string
+
+	// call calls a generator function. it's nil for an expectation.
+	call func(g *generator.Generator) (interface{}, bool, error)
+
+	// want is the result that an expectation expects
+	want result
+}
+
+type result struct {
+	call   string
+	value  interface{}
+	isDone bool
+	err    error
+}
+
+// String formats the result like "yield 1" or "return <nil>, error e1".
+func (r result) String() string {
+	switch {
+	case !r.isDone && r.err != nil:
+		return fmt.Sprintf("error %v", r.err)
+	case !r.isDone:
+		return fmt.Sprintf("yield %s", formatValue(r.value))
+	case r.err != nil:
+		return fmt.Sprintf("return %s, error %v", formatValue(r.value), r.err)
+	}
+	return fmt.Sprintf("return %s", formatValue(r.value))
+}
+
+// New creates an empty script.
+func New() Script {
+	return Script{}
+}
+
+// Timeout sets how long each call of the consumer may take.
+func (s Script) Timeout(d time.Duration) Script {
+	s.timeout = d
+	return s
+}
+
+// Send calls `Generator#Next` with the value.
+func (s Script) Send(value interface{}) Script {
+	return s.with(step{
+		name: fmt.Sprintf("Next(%s)", formatValue(value)),
+		call: func(g *generator.Generator) (interface{}, bool, error) {
+			return g.Next(value)
+		},
+	})
+}
+
+// ThrowIn calls `Generator#Error` with the error.
+func (s Script) ThrowIn(err error) Script {
+	return s.with(step{
+		name: fmt.Sprintf("Error(%v)", err),
+		call: func(g *generator.Generator) (interface{}, bool, error) {
+			return g.Error(err)
+		},
+	})
+}
+
+// Stop calls `Generator#Return` with the value.
+func (s Script) Stop(value interface{}) Script {
+	return s.with(step{
+		name: fmt.Sprintf("Return(%s)", formatValue(value)),
+		call: func(g *generator.Generator) (interface{}, bool, error) {
+			return g.Return(value)
+		},
+	})
+}
+
+// ExpectYield expects the `Func` to yield the value. Values are compared
+// using `reflect.DeepEqual`.
+func (s Script) ExpectYield(value interface{}) Script {
+	return s.with(step{
+		name: fmt.Sprintf("ExpectYield(%s)", formatValue(value)),
+		want: result{value: value},
+	})
+}
+
+// ExpectError expects the `Func` to deliver an error that matches err
+// using `errors.Is`.
+func (s Script) ExpectError(err error) Script {
+	return s.with(step{
+		name: fmt.Sprintf("ExpectError(%v)", err),
+		want: result{err: err},
+	})
+}
+
+// ExpectReturn expects the `Func` to be done with the value and without
+// an error.
+func (s Script) ExpectReturn(value interface{}) Script {
+	return s.with(step{
+		name: fmt.Sprintf("ExpectReturn(%s)", formatValue(value)),
+		want: result{value: value, isDone: true},
+	})
+}
+
+// ExpectFail expects the `Func` to be done with an error that matches
+// err using `errors.Is`, regardless of the value it returned.
+func (s Script) ExpectFail(err error) Script {
+	return s.with(step{
+		name: fmt.Sprintf("ExpectFail(%v)", err),
+		want: result{err: err, isDone: true},
+	})
+}
+
+// with returns a copy of the script with the step added. the steps are
+// copied so that scripts built from the same script don't share them.
+func (s Script) with(st step) Script {
+	steps := make([]step, len(s.steps), len(s.steps)+1)
+	copy(steps, s.steps)
+	s.steps = append(steps, st)
+	return s
+}
+
+// Run creates a generator for fn using the options and plays the script
+// against it. It fails the test with `testing.TB#Fatalf` at the first
+// step that fails, so it should be called from the goroutine of the
+// test.
+func (s Script) Run(tb testing.TB, fn generator.Func, options ...generator.Option) {
+	tb.Helper()
+
+	timeout := s.timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	p := &player{tb: tb, g: generator.New(fn, options...), timeout: timeout}
+	for _, st := range s.steps {
+		if st.call != nil {
+			p.call(st.name, st.call)
+		} else {
+			p.expect(st.name, st.want)
+		}
+	}
+}
+
+// player plays a script against a generator.
+type player struct {
+	tb      testing.TB
+	g       *generator.Generator
+	timeout time.Duration
+
+	// last is the result of the last call. it's nil if it has been
+	// checked by an expectation.
+	last *result
+
+	// history is the conversation so far, which is shown when the test
+	// fails.
+	history []string
+}
+
+func (p *player) call(name string, fn func(g *generator.Generator) (interface{}, bool, error)) {
+	p.tb.Helper()
+
+	resultChan := make(chan result, 1)
+	go func() {
+		value, isDone, err := fn(p.g)
+		resultChan <- result{name, value, isDone, err}
+	}()
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+	select {
+	case r := <-resultChan:
+		p.last = &r
+		p.history = append(p.history, fmt.Sprintf("%s -> %v", name, r))
+	case <-timer.C:
+		p.history = append(p.history, fmt.Sprintf("%s -> ?", name))
+		p.fail("%s didn't return within %v. the Func may be deadlocked", name, p.timeout)
+	}
+}
+
+func (p *player) expect(name string, want result) {
+	p.tb.Helper()
+
+	if p.last == nil {
+		p.call("Next(<nil>)", func(g *generator.Generator) (interface{}, bool, error) {
+			return g.Next(nil)
+		})
+	}
+	got := *p.last
+	p.last = nil
+
+	if !matches(got, want) {
+		p.fail("%s after %s:\n\tgot:  %v\n\twant: %v", name, got.call, got, want)
+	}
+}
+
+func (p *player) fail(format string, args ...interface{}) {
+	p.tb.Helper()
+	p.tb.Fatalf("generatortest: "+format+"\nconversation:\n\t%s",
+		append(args, strings.Join(p.history, "\n\t"))...)
+}
+func matches(got, want result) bool {
+	if got.isDone != want.isDone {
+		return false
+	}
+	if want.err != nil {
+		// the value of a failed `Func` isn't checked
+		return errors.Is(got.err, want.err) && (got.isDone || got.value == nil)
+	}
+	return got.err == nil && reflect.DeepEqual(got.value, want.value)
+}
+
+func formatValue(v interface{}) string {
+	if v == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%#v", v)
+}
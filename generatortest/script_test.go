@@ -0,0 +1,138 @@
+package generatortest_test
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/generatortest"
+)
+
+var (
+	errBad      = errors.New("bad")
+	errRejected = errors.New("rejected")
+)
+
+func echo(gc *generator.Controller) (interface{}, error) {
+	value, shouldReturn, err := gc.Yield(1)
+	for i := 2; ; i++ {
+		switch {
+		case shouldReturn:
+			return value, nil
+		case err != nil:
+			value, shouldReturn, err = gc.Error(fmt.Errorf("%w: %v", errRejected, err))
+		case value == "fail":
+			return nil, errBad
+		default:
+			value, shouldReturn, err = gc.Yield(i)
+		}
+	}
+}
+
+func TestScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		script generatortest.Script
+	}{
+		{`passing`, generatortest.New().
+			ExpectYield(1).
+			Send("a").ExpectYield(2).
+			ThrowIn(errBad).ExpectError(errRejected).
+			Stop("r").ExpectReturn("r")},
+		{`ExpectFail`, generatortest.New().
+			ExpectYield(1).
+			Send("fail").ExpectFail(errBad).
+			ExpectReturn(nil)},
+		{`unchecked results`, generatortest.New().
+			Send(nil).Send(nil).Send(nil).ExpectYield(3)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.script.Run(t, echo)
+		})
+	}
+}
+
+func TestScript_Replay(t *testing.T) {
+	first := generatortest.New().ExpectYield(1)
+
+	// the scripts built from first don't change it or each other
+	stop := first.Stop("r").ExpectReturn("r")
+	next := first.Send(nil).ExpectYield(2)
+	for i := 0; i < 2; i++ {
+		first.Run(t, echo)
+		stop.Run(t, echo)
+		next.Run(t, echo)
+	}
+
+	// the options are used to create the generator
+	recorder := &generator.TraceRecorder{}
+	first.Run(t, echo, generator.WithTracer(recorder))
+	if len(recorder.Events()) == 0 {
+		t.Fatal("the generator wasn't traced")
+	}
+}
+
+func TestScript_Failing(t *testing.T) {
+	t.Run(`unexpected result`, func(t *testing.T) {
+		msg := runFailing(func(tb testing.TB) {
+			generatortest.New().
+				ExpectYield(1).
+				Send("a").ExpectYield(3).
+				Run(tb, echo)
+		})
+		want := "generatortest: ExpectYield(3) after Next(\"a\"):\n" +
+			"\tgot:  yield 2\n" +
+			"\twant: yield 3\n" +
+			"conversation:\n" +
+			"\tNext(<nil>) -> yield 1\n" +
+			"\tNext(\"a\") -> yield 2"
+		if msg != want {
+			t.Fatalf("got: %q. wanted: %q", msg, want)
+		}
+	})
+	t.Run(`deadlock`, func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+		msg := runFailing(func(tb testing.TB) {
+			generatortest.New().Timeout(10*time.Millisecond).ExpectReturn(nil).Run(tb,
+				func(gc *generator.Controller) (interface{}, error) {
+					<-block
+					return nil, nil
+				},
+			)
+		})
+		if !strings.HasPrefix(msg, "generatortest: Next(<nil>) didn't return within 10ms") {
+			t.Fatalf("got: %q. wanted: a timeout", msg)
+		}
+	})
+}
+
+// fakeTB records the message of `Fatalf`.
+type fakeTB struct {
+	testing.TB
+	msg string
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Fatalf(format string, args ...interface{}) {
+	tb.msg = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// runFailing runs the script and returns the message it failed with.
+func runFailing(script func(tb testing.TB)) string {
+	tb := &fakeTB{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		script(tb)
+	}()
+	<-done
+	return tb.msg
+}
@@ -1,3 +1,3 @@
 module github.com/bmdelacruz/generator
 
-go 1.13
+go 1.20
@@ -0,0 +1,237 @@
+// Package httpstream serves the values yielded by generators as HTTP
+// streaming responses, either as Server-Sent Events or as chunked
+// newline-delimited JSON.
+package httpstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// Format is the format of the response written by a `Handler`.
+type Format int
+
+const (
+	// SSE writes each value as a Server-Sent Event.
+	SSE Format = iota
+
+	// NDJSON writes each value as a line of JSON.
+	NDJSON
+)
+
+// Event can be yielded by the generator served in the `SSE` format to
+// control the fields of the event that will be written. Any other value
+// is written as the data of an unnamed event without an ID.
+//
+// When an `Event` is served in the `NDJSON` format, only its data is
+// written.
+type Event struct {
+	// ID is the ID of the event. The client sends the ID of the last
+	// event it received in the `Last-Event-ID` header when it reconnects.
+	ID string
+
+	// Event is the name of the event.
+	Event string
+
+	// Data is the data of the event. Strings and byte slices are written
+	// as is; other values are encoded using `json.Marshal`. In the
+	// `NDJSON` format, strings are encoded as JSON strings while byte
+	// slices should already contain a single line of JSON.
+	Data interface{}
+
+	// Retry is the reconnection time the client should use. It's not
+	// written if zero.
+	Retry time.Duration
+}
+
+// Handler is an `http.Handler` that creates a generator for each request
+// and writes each of the values it yields to the response, flushing the
+// response after every value.
+//
+// The generator is driven by calling `Generator#Next` with a nil value.
+// An error delivered by the generator is written as an event named
+// "error" in the `SSE` format or as an `{"error":"..."}` line in the
+// `NDJSON` format and, unless the generator is done, the handler
+// continues to pull values from it.
+//
+// When the request's context is done, which happens when the client
+// disconnects, the handler returns without waiting for the pending
+// `Generator#Next` call and stops the generator with
+// `Generator#Return` as soon as the call returns. The generator can
+// watch the request's context to stop sooner.
+type Handler struct {
+	// New creates the generator for the request. It is required.
+	New func(r *http.Request) (*generator.Generator, error)
+
+	// Resume, if not nil, creates the generator for a request that has a
+	// `Last-Event-ID` header, which is passed as lastEventID. It's called
+	// instead of New so that the generator can continue after the last
+	// event that the client has received.
+	Resume func(r *http.Request, lastEventID string) (*generator.Generator, error)
+
+	// Format is the format of the response.
+	Format Format
+}
+
+type nextResult struct {
+	value  interface{}
+	isDone bool
+	err    error
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		g   *generator.Generator
+		err error
+	)
+	if id := r.Header.Get("Last-Event-ID"); id != "" && h.Resume != nil {
+		g, err = h.Resume(r, id)
+	} else {
+		g, err = h.New(r)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch h.Format {
+	case SSE:
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+	case NDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	ctx := r.Context()
+
+	for {
+		// call `Next` from another goroutine so that the handler can
+		// return as soon as the client disconnects
+		resultChan := make(chan nextResult, 1)
+		go func() {
+			value, isDone, err := g.Next(nil)
+			resultChan <- nextResult{value, isDone, err}
+		}()
+
+		var res nextResult
+		select {
+		case res = <-resultChan:
+		case <-ctx.Done():
+			go func() {
+				if res := <-resultChan; !res.isDone {
+					g.Return(nil)
+				}
+			}()
+			return
+		}
+
+		if res.isDone && res.err == nil {
+			return
+		}
+
+		var werr error
+		if res.err != nil {
+			werr = h.writeError(w, res.err)
+		} else {
+			werr = h.writeValue(w, res.value)
+		}
+		if werr == nil && flusher != nil {
+			flusher.Flush()
+		}
+
+		if res.isDone {
+			return
+		}
+		if werr != nil || ctx.Err() != nil {
+			g.Return(nil)
+			return
+		}
+	}
+}
+
+func (h *Handler) writeValue(w io.Writer, value interface{}) error {
+	event, ok := value.(Event)
+	if !ok {
+		event = Event{Data: value}
+	}
+
+	data, err := encodeData(event.Data)
+	if err != nil {
+		return err
+	}
+
+	if h.Format == NDJSON {
+		if _, ok := event.Data.(string); ok {
+			// strings are written as JSON strings to keep every line a
+			// valid JSON value
+			if data, err = json.Marshal(event.Data); err != nil {
+				return err
+			}
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+	return writeEvent(w, event, data)
+}
+
+func (h *Handler) writeError(w io.Writer, err error) error {
+	if h.Format == NDJSON {
+		data, _ := json.Marshal(map[string]string{"error": err.Error()})
+		_, werr := w.Write(append(data, '\n'))
+		return werr
+	}
+	return writeEvent(w, Event{Event: "error"}, []byte(err.Error()))
+}
+
+// writeEvent writes event to w in the event stream format, using data
+// as the event's data.
+func writeEvent(w io.Writer, event Event, data []byte) error {
+	var b bytes.Buffer
+	if event.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", sanitize(event.ID))
+	}
+	if event.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", sanitize(event.Event))
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", event.Retry.Milliseconds())
+	}
+	// the data may span multiple lines. each of them needs its own field.
+	// a lone "\r" ends a line too, so it can't be used to inject fields.
+	for _, line := range strings.Split(lineBreaks.Replace(string(data)), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteByte('\n')
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func encodeData(data interface{}) ([]byte, error) {
+	switch d := data.(type) {
+	case string:
+		return []byte(d), nil
+	case []byte:
+		return d, nil
+	}
+	return json.Marshal(data)
+}
+
+// lineBreaks replaces the line breaks of the event stream format, which
+// are "\r\n", "\r" and "\n", with "\n".
+var lineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// sanitize removes the line breaks from s so that it can't end a field
+// early.
+func sanitize(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,162 @@
+package httpstream_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/httpstream"
+)
+
+func TestHandler_SSE(t *testing.T) {
+	h := &httpstream.Handler{
+		New: func(r *http.Request) (*generator.Generator, error) {
+			return generator.New(
+				func(gc *generator.Controller) (interface{}, error) {
+					gc.Yield("a\nb")
+					gc.Yield("x\rid: 5\revent: admin\r\ny")
+					gc.Yield(httpstream.Event{ID: "2", Event: "progress", Data: map[string]int{"done": 50}})
+					gc.Error(fmt.Errorf("e1"))
+					gc.Yield(httpstream.Event{ID: "3", Retry: time.Second, Data: []byte("c")})
+					return nil, nil
+				},
+			), nil
+		},
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("got: %v. wanted: text/event-stream", ct)
+	}
+	want := "data: a\ndata: b\n\n" +
+		"data: x\ndata: id: 5\ndata: event: admin\ndata: y\n\n" +
+		"id: 2\nevent: progress\ndata: {\"done\":50}\n\n" +
+		"event: error\ndata: e1\n\n" +
+		"id: 3\nretry: 1000\ndata: c\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("got: %q. wanted: %q", got, want)
+	}
+}
+
+func TestHandler_NDJSON(t *testing.T) {
+	h := &httpstream.Handler{
+		Format: httpstream.NDJSON,
+		New: func(r *http.Request) (*generator.Generator, error) {
+			return generator.New(
+				func(gc *generator.Controller) (interface{}, error) {
+					gc.Yield("a")
+					gc.Yield(httpstream.Event{ID: "2", Data: []int{1, 2}})
+					return nil, fmt.Errorf("e1")
+				},
+			), nil
+		},
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	want := "\"a\"\n[1,2]\n{\"error\":\"e1\"}\n"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("got: %q. wanted: %q", got, want)
+	}
+}
+
+func TestHandler_Resume(t *testing.T) {
+	count := func(from int) *generator.Generator {
+		return generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				for i := from; i < 3; i++ {
+					gc.Yield(httpstream.Event{ID: strconv.Itoa(i), Data: i})
+				}
+				return nil, nil
+			},
+		)
+	}
+	h := &httpstream.Handler{
+		New: func(r *http.Request) (*generator.Generator, error) {
+			return count(0), nil
+		},
+		Resume: func(r *http.Request, lastEventID string) (*generator.Generator, error) {
+			last, err := strconv.Atoi(lastEventID)
+			if err != nil {
+				return nil, err
+			}
+			return count(last + 1), nil
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got, want := rec.Body.String(), "id: 2\ndata: 2\n\n"; got != want {
+		t.Fatalf("got: %q. wanted: %q", got, want)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Last-Event-ID", "x")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("got: %v. wanted: %v", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestHandler_Disconnect(t *testing.T) {
+	stopped := make(chan struct{})
+	srv := httptest.NewServer(&httpstream.Handler{
+		New: func(r *http.Request) (*generator.Generator, error) {
+			return generator.New(
+				func(gc *generator.Controller) (interface{}, error) {
+					defer close(stopped)
+					for i := 0; ; i++ {
+						if _, shouldReturn, _ := gc.Yield(i); shouldReturn {
+							return nil, nil
+						}
+					}
+				},
+			), nil
+		},
+	})
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	if line != "data: 0\n" || err != nil {
+		t.Fatalf("got: %q, %v. wanted: %q, <nil>", line, err, "data: 0\n")
+	}
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("generator was not stopped after the client disconnected")
+	}
+}
+
+func TestHandler_NewError(t *testing.T) {
+	h := &httpstream.Handler{
+		New: func(r *http.Request) (*generator.Generator, error) {
+			return nil, errors.New("e1")
+		},
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("got: %v. wanted: %v", rec.Code, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,233 @@
+// Package lex is a framework for writing lexers out of state functions,
+// like the one described in Rob Pike's "Lexical Scanning in Go" talk,
+// whose tokens are delivered by a generator.
+//
+// A state function reads the input using the `Lexer`'s cursor, emits
+// the tokens it finds and returns the next state function:
+//
+//	func lexNumber(l *lex.Lexer) lex.StateFn {
+//		l.AcceptRun("0123456789")
+//		l.Emit(Number)
+//		return lexSpace
+//	}
+//
+// Each emitted token is yielded using `Controller#Yield` so the lexer
+// only runs as far as the consumer of the generator has asked for.
+package lex
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// EOF is returned by `Lexer#Next` and `Lexer#Peek` when the end of the
+// input has been reached.
+const EOF rune = -1
+
+// Type identifies the type of a token. The types are defined by the
+// lexer.
+type Type int
+
+// Pos is a position within the input.
+type Pos struct {
+	// Offset is the byte offset, starting from 0.
+	Offset int
+
+	// Line is the line number, starting from 1.
+	Line int
+
+	// Column is the rune offset within the line, starting from 1.
+	Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Token is the value yielded by the generator created by `Run`.
+type Token struct {
+	Type  Type
+	Value string
+
+	// Pos is the position of the start of the token.
+	Pos Pos
+}
+
+// Error is the error delivered by the generator when the lexer calls
+// `Lexer#Errorf`.
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %s", e.Pos, e.Msg)
+}
+
+// StateFn is a state of the lexer. It returns the next state, or nil to
+// stop lexing.
+type StateFn func(l *Lexer) StateFn
+
+// Lexer holds the state of the lexer. The text between the start of the
+// current token and the cursor is the value of the token that will be
+// emitted next.
+type Lexer struct {
+	gc    *generator.Controller
+	input string
+
+	// start is the position of the start of the current token. pos is
+	// the offset of the cursor and width is the width of the last rune
+	// read by `Next`.
+	start Pos
+	pos   int
+	width int
+
+	// stopped is set when the consumer called `Generator#Return` or
+	// `Generator#Error`. returnValue and err are the values that should
+	// be returned by the `Func`.
+	stopped     bool
+	returnValue interface{}
+	err         error
+}
+
+// Run creates a generator that lexes the input, starting from the state
+// start, and yields each of the emitted tokens.
+//
+// Calling `Generator#Return` or `Generator#Error` stops the lexer after
+// the current state function returns; the `Func` will return the value
+// or the error passed to them.
+func Run(input string, start StateFn) *generator.Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			l := &Lexer{
+				gc:    gc,
+				input: input,
+				start: Pos{Line: 1, Column: 1},
+			}
+			for state := start; state != nil && !l.stopped; {
+				state = state(l)
+			}
+			return l.returnValue, l.err
+		},
+	)
+}
+
+// Next reads the next rune of the input and moves the cursor past it.
+// It returns `EOF` at the end of the input.
+func (l *Lexer) Next() rune {
+	if l.pos >= len(l.input) {
+		l.width = 0
+		return EOF
+	}
+	r, w := utf8.DecodeRuneInString(l.input[l.pos:])
+	l.width = w
+	l.pos += w
+	return r
+}
+
+// Backup moves the cursor back by one rune. It can only be called once
+// per call of `Next`.
+func (l *Lexer) Backup() {
+	l.pos -= l.width
+	l.width = 0
+}
+
+// Peek returns the next rune of the input without moving the cursor.
+func (l *Lexer) Peek() rune {
+	r := l.Next()
+	l.Backup()
+	return r
+}
+
+// Accept moves the cursor past the next rune if it's one of the runes
+// in valid, and reports whether it did.
+func (l *Lexer) Accept(valid string) bool {
+	if strings.ContainsRune(valid, l.Next()) {
+		return true
+	}
+	l.Backup()
+	return false
+}
+
+// AcceptRun moves the cursor past the run of runes that are in valid
+// and returns the number of runes it moved past.
+func (l *Lexer) AcceptRun(valid string) int {
+	n := 0
+	for l.Accept(valid) {
+		n++
+	}
+	return n
+}
+
+// Value returns the text between the start of the current token and the
+// cursor.
+func (l *Lexer) Value() string {
+	return l.input[l.start.Offset:l.pos]
+}
+
+// Start returns the position of the start of the current token.
+func (l *Lexer) Start() Pos {
+	return l.start
+}
+
+// Pos returns the position of the cursor.
+func (l *Lexer) Pos() Pos {
+	return l.advance(l.start, l.pos)
+}
+
+// Emit yields a token of the type t with the current token's text as
+// its value and starts the next token at the cursor.
+func (l *Lexer) Emit(t Type) {
+	token := Token{Type: t, Value: l.Value(), Pos: l.start}
+	l.Ignore()
+	l.handle(l.gc.Yield(token))
+}
+
+// Ignore skips the current token's text and starts the next token at
+// the cursor.
+func (l *Lexer) Ignore() {
+	l.start = l.advance(l.start, l.pos)
+}
+
+// Errorf delivers an `*Error` at the start of the current token with the
+// formatted message using `Controller#Error`. It returns nil so that a
+// state function can stop lexing with `return l.Errorf(...)`.
+func (l *Lexer) Errorf(format string, args ...interface{}) StateFn {
+	l.handle(l.gc.Error(&Error{Pos: l.start, Msg: fmt.Sprintf(format, args...)}))
+	return nil
+}
+
+// Stopped reports whether the consumer has stopped the lexer. The
+// current state function can return early when it's true.
+func (l *Lexer) Stopped() bool {
+	return l.stopped
+}
+
+func (l *Lexer) handle(value interface{}, shouldReturn bool, err error) {
+	if l.stopped {
+		return
+	}
+	if shouldReturn {
+		l.stopped, l.returnValue = true, value
+	} else if err != nil {
+		l.stopped, l.err = true, err
+	}
+}
+
+// advance returns the position at the offset, which should not be
+// before p.
+func (l *Lexer) advance(p Pos, offset int) Pos {
+	for _, r := range l.input[p.Offset:offset] {
+		if r == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	p.Offset = offset
+	return p
+}
@@ -0,0 +1,134 @@
+package lex_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/lex"
+)
+
+const (
+	Number lex.Type = iota
+	Ident
+	Op
+)
+
+const (
+	digits  = "0123456789"
+	letters = "abcdefghijklmnopqrstuvwxyz"
+)
+
+func lexAny(l *lex.Lexer) lex.StateFn {
+	switch r := l.Peek(); {
+	case r == lex.EOF:
+		return nil
+	case r == ' ' || r == '\n':
+		l.AcceptRun(" \n")
+		l.Ignore()
+		return lexAny
+	case l.Accept(digits):
+		l.AcceptRun(digits)
+		l.Emit(Number)
+		return lexAny
+	case l.Accept(letters):
+		l.AcceptRun(letters + digits)
+		l.Emit(Ident)
+		return lexAny
+	case l.Accept("+-*/="):
+		l.Emit(Op)
+		return lexAny
+	default:
+		l.Next()
+		return l.Errorf("unexpected %q", r)
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Run(`tokens`, func(t *testing.T) {
+		g := lex.Run("x1 = 42\n  + é", lexAny)
+		tokens, err := collect(g)
+		want := []lex.Token{
+			{Ident, "x1", lex.Pos{Offset: 0, Line: 1, Column: 1}},
+			{Op, "=", lex.Pos{Offset: 3, Line: 1, Column: 4}},
+			{Number, "42", lex.Pos{Offset: 5, Line: 1, Column: 6}},
+			{Op, "+", lex.Pos{Offset: 10, Line: 2, Column: 3}},
+		}
+		if !reflect.DeepEqual(tokens, want) {
+			t.Fatalf("got: %v. wanted: %v", tokens, want)
+		}
+		var lexErr *lex.Error
+		if !errors.As(err, &lexErr) || lexErr.Pos != (lex.Pos{Offset: 12, Line: 2, Column: 5}) {
+			t.Fatalf("got: %v. wanted: an error at 2:5", err)
+		}
+		if lexErr.Error() != `2:5: unexpected 'é'` {
+			t.Fatalf("got: %v. wanted: 2:5: unexpected 'é'", lexErr.Error())
+		}
+	})
+	t.Run(`Return("r")`, func(t *testing.T) {
+		calls := 0
+		g := lex.Run("a b c d", func(l *lex.Lexer) lex.StateFn {
+			calls++
+			return lexAny(l)
+		})
+		g.Next(nil)
+		v, isDone, err := g.Return("r")
+		if v != "r" || !isDone || err != nil || calls != 1 {
+			t.Fatalf("got: %v, %v, %v, %v. wanted: r, true, <nil>, 1", v, isDone, err, calls)
+		}
+	})
+}
+
+func TestLexer_Cursor(t *testing.T) {
+	g := lex.Run("ab\ncd", func(l *lex.Lexer) lex.StateFn {
+		if r := l.Next(); r != 'a' {
+			return l.Errorf("Next: got %q", r)
+		}
+		l.Backup()
+		if r := l.Peek(); r != 'a' {
+			return l.Errorf("Peek: got %q", r)
+		}
+		if n := l.AcceptRun("ab\n"); n != 3 {
+			return l.Errorf("AcceptRun: got %d", n)
+		}
+		if p := l.Pos(); p != (lex.Pos{Offset: 3, Line: 2, Column: 1}) {
+			return l.Errorf("Pos: got %v", p)
+		}
+		if l.Accept("x") {
+			return l.Errorf("Accept: got true")
+		}
+		if v := l.Value(); v != "ab\n" {
+			return l.Errorf("Value: got %q", v)
+		}
+		l.Ignore()
+		l.AcceptRun("cd")
+		if r := l.Next(); r != lex.EOF {
+			return l.Errorf("Next: got %q", r)
+		}
+		l.Backup()
+		l.Emit(Ident)
+		return nil
+	})
+	tokens, err := collect(g)
+	want := []lex.Token{{Ident, "cd", lex.Pos{Offset: 3, Line: 2, Column: 1}}}
+	if !reflect.DeepEqual(tokens, want) || err != nil {
+		t.Fatalf("got: %v, %v. wanted: %v, <nil>", tokens, err, want)
+	}
+}
+
+// collect calls `Next` until g is done and returns the tokens and the
+// first error that was received.
+func collect(g *generator.Generator) ([]lex.Token, error) {
+	var tokens []lex.Token
+	for {
+		v, isDone, err := g.Next(nil)
+		if err != nil {
+			return tokens, err
+		}
+		if isDone {
+			return tokens, nil
+		}
+		tokens = append(tokens, v.(lex.Token))
+	}
+}
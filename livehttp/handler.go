@@ -0,0 +1,36 @@
+// Package livehttp serves the registry of live generators over HTTP,
+// like `net/http/pprof` serves the profiles. It's kept apart from the
+// generator package so that the generator package doesn't depend on
+// `net/http`.
+package livehttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// Handler returns a handler that renders the generators in the
+// registry like `generator.DumpLive`, or as a JSON array of
+// `generator.LiveGenerator` when the request has a "format=json" query
+// or accepts "application/json":
+//
+//	generator.SetRegistryEnabled(true)
+//	http.Handle("/debug/generators", livehttp.Handler())
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if r.URL.Query().Get("format") == "json" ||
+			strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(generator.LiveGenerators())
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		generator.DumpLive(w)
+	})
+}
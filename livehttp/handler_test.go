@@ -0,0 +1,60 @@
+package livehttp_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/livehttp"
+)
+
+func TestHandler(t *testing.T) {
+	generator.SetRegistryEnabled(true)
+	defer generator.SetRegistryEnabled(false)
+
+	g := generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			gc.Yield(1)
+			return nil, nil
+		},
+		generator.WithName("numbers"),
+	)
+	g.Next(nil)
+	defer g.Return(nil)
+
+	t.Run(`JSON`, func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		livehttp.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/?format=json", nil))
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("got: %v. wanted: application/json", ct)
+		}
+		var live []struct {
+			ID    uint64 `json:"id"`
+			State string `json:"state"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &live); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(live) != 1 || live[0].ID != g.ID() || live[0].State != "suspended" {
+			t.Fatalf("got: %v. wanted: the suspended generator %v", rec.Body.String(), g.ID())
+		}
+	})
+	t.Run(`Accept`, func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", "application/json")
+		livehttp.Handler().ServeHTTP(rec, req)
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("got: %v. wanted: application/json", ct)
+		}
+	})
+	t.Run(`text`, func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		livehttp.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if !strings.Contains(rec.Body.String(), `"numbers" [suspended, `) {
+			t.Fatalf("got: %v. wanted: a text dump", rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package generator
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics receives measurements from the generators it was passed to
+// using `WithMetrics` or `SetDefaultMetrics`. Its methods are called
+// synchronously from the goroutine of the consumer, of the `Func` or of
+// the finalizer, so they should return quickly and be safe for
+// concurrent use. `ExpvarMetrics` is an implementation that can be
+// published using `expvar`; other backends can implement this interface.
+type Metrics interface {
+	// Created is called when a generator is created, which is also when
+	// the goroutine of its `Func` is spawned.
+	Created(id uint64)
+
+	// Finished is called when the `Func` of a generator has returned.
+	Finished(id uint64)
+
+	// Leaked is called when a generator was garbage collected before
+	// its `Func` returned. The goroutine of the `Func` will never end
+	// unless the `Func` stops on its own. `Finished` isn't called for
+	// a leaked generator even if its `Func` returns later, like after
+	// `Controller#YieldTimeout` gives up.
+	Leaked(id uint64)
+
+	// Yielded is called when the `Func` calls `Controller#Yield`.
+	Yielded(id uint64)
+
+	// Errored is called when the `Func` calls `Controller#Error` or
+	// returns an error.
+	Errored(id uint64)
+
+	// ConsumerWaited is called when a call to `Generator#Next`,
+	// `Generator#Return` or `Generator#Error` returns with how long the
+	// consumer waited for the `Func`.
+	ConsumerWaited(id uint64, d time.Duration)
+
+	// ProducerWaited is called when a controller function returns with
+	// how long the `Func` waited for the consumer.
+	ProducerWaited(id uint64, d time.Duration)
+}
+
+// WithMetrics makes the generator report its measurements to metrics
+// instead of the default metrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(g *Generator) {
+		g.metrics = metrics
+	}
+}
+
+// defaultMetricsValue holds a `metricsHolder` since `atomic.Value`
+// can't store nil or values of different types.
+var defaultMetricsValue atomic.Value
+
+type metricsHolder struct {
+	metrics Metrics
+}
+
+// SetDefaultMetrics sets the metrics of the generators that are created
+// without `WithMetrics` after it's called. Passing nil disables the
+// default metrics, which is the initial setting.
+func SetDefaultMetrics(metrics Metrics) {
+	defaultMetricsValue.Store(metricsHolder{metrics})
+}
+
+func defaultMetrics() Metrics {
+	h, _ := defaultMetricsValue.Load().(metricsHolder)
+	return h.metrics
+}
+
+func (g *state) observeConsumerWait(start time.Time) {
+	g.metrics.ConsumerWaited(g.id, time.Since(start))
+}
+
+func (g *state) observeProducerWait(start time.Time) {
+	g.metrics.ProducerWaited(g.id, time.Since(start))
+}
+
+// finalize is called when the generator is garbage collected.
+func (g *Generator) finalize() {
+	if !atomic.CompareAndSwapInt32(&g.terminal, terminalNone, terminalLeaked) {
+		return
+	}
+	if g.metrics != nil {
+		g.metrics.Leaked(g.id)
+	}
+	if g.debug != nil {
+		g.debug.mu.Lock()
+		g.debug.leaked = true
+		g.debug.mu.Unlock()
+	}
+}
+
+// histogramBounds are the upper bounds of the buckets of a `Histogram`.
+var histogramBounds = []time.Duration{
+	time.Microsecond,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// Histogram counts durations in buckets whose upper bounds grow by a
+// factor of ten from 1µs to 10s, plus a bucket for longer durations. It
+// implements `expvar.Var`. It's safe for concurrent use.
+type Histogram struct {
+	mu     sync.Mutex
+	counts [9]uint64
+	count  uint64
+	sum    time.Duration
+}
+
+// Observe counts the duration.
+func (h *Histogram) Observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for i < len(histogramBounds) && d > histogramBounds[i] {
+		i++
+	}
+	h.counts[i]++
+	h.count++
+	h.sum += d
+}
+
+// HistogramSnapshot is the state of a `Histogram` at some point.
+type HistogramSnapshot struct {
+	Count uint64 `json:"count"`
+	SumNs int64  `json:"sum_ns"`
+
+	// Buckets maps the upper bounds of the buckets, like "1ms" or
+	// "+Inf", to the number of durations in them.
+	Buckets map[string]uint64 `json:"buckets"`
+}
+
+// Snapshot returns the current state of the histogram.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buckets := make(map[string]uint64, len(h.counts))
+	for i, c := range h.counts {
+		if i < len(histogramBounds) {
+			buckets[histogramBounds[i].String()] = c
+		} else {
+			buckets["+Inf"] = c
+		}
+	}
+	return HistogramSnapshot{Count: h.count, SumNs: int64(h.sum), Buckets: buckets}
+}
+
+// String returns the snapshot of the histogram encoded as JSON.
+func (h *Histogram) String() string {
+	b, _ := json.Marshal(h.Snapshot())
+	return string(b)
+}
@@ -0,0 +1,187 @@
+package generator
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ExpvarMetrics is a `Metrics` implementation that keeps aggregate
+// counters and histograms, as well as the counters and histograms of
+// each live generator. It implements `expvar.Var` so it can be published
+// with `expvar.Publish`:
+//
+//	m := generator.NewExpvarMetrics()
+//	expvar.Publish("generators", m)
+//	generator.SetDefaultMetrics(m)
+type ExpvarMetrics struct {
+	created  uint64
+	finished uint64
+	leaked   uint64
+	yields   uint64
+	errors   uint64
+
+	consumerWait Histogram
+	producerWait Histogram
+
+	mu         sync.Mutex
+	generators map[uint64]*generatorMetrics
+}
+
+type generatorMetrics struct {
+	yields       uint64
+	errors       uint64
+	consumerWait Histogram
+	producerWait Histogram
+}
+
+// NewExpvarMetrics creates an empty `ExpvarMetrics`.
+func NewExpvarMetrics() *ExpvarMetrics {
+	return &ExpvarMetrics{generators: make(map[uint64]*generatorMetrics)}
+}
+
+// Created counts the generator as live.
+func (m *ExpvarMetrics) Created(id uint64) {
+	atomic.AddUint64(&m.created, 1)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generators[id] = &generatorMetrics{}
+}
+
+// Finished stops counting the generator as live and drops its own
+// measurements.
+func (m *ExpvarMetrics) Finished(id uint64) {
+	atomic.AddUint64(&m.finished, 1)
+	m.drop(id)
+}
+
+// Leaked counts the generator as leaked and drops its own measurements.
+func (m *ExpvarMetrics) Leaked(id uint64) {
+	atomic.AddUint64(&m.leaked, 1)
+	m.drop(id)
+}
+
+// Yielded counts the yield.
+func (m *ExpvarMetrics) Yielded(id uint64) {
+	atomic.AddUint64(&m.yields, 1)
+	if gm := m.get(id); gm != nil {
+		atomic.AddUint64(&gm.yields, 1)
+	}
+}
+
+// Errored counts the error.
+func (m *ExpvarMetrics) Errored(id uint64) {
+	atomic.AddUint64(&m.errors, 1)
+	if gm := m.get(id); gm != nil {
+		atomic.AddUint64(&gm.errors, 1)
+	}
+}
+
+// ConsumerWaited adds the duration to the consumer wait histograms.
+func (m *ExpvarMetrics) ConsumerWaited(id uint64, d time.Duration) {
+	m.consumerWait.Observe(d)
+	if gm := m.get(id); gm != nil {
+		gm.consumerWait.Observe(d)
+	}
+}
+
+// ProducerWaited adds the duration to the producer wait histograms.
+func (m *ExpvarMetrics) ProducerWaited(id uint64, d time.Duration) {
+	m.producerWait.Observe(d)
+	if gm := m.get(id); gm != nil {
+		gm.producerWait.Observe(d)
+	}
+}
+
+// Live returns the number of generators whose `Func` hasn't returned
+// and that haven't leaked.
+func (m *ExpvarMetrics) Live() uint64 {
+	finished := atomic.LoadUint64(&m.finished)
+	leaked := atomic.LoadUint64(&m.leaked)
+	return live(atomic.LoadUint64(&m.created), finished, leaked)
+}
+
+// live returns the number of live generators. created has to be loaded
+// after finished and leaked so that it counts every generator that they
+// count. the result is clamped at 0 since the methods of the metrics can
+// also be called by something other than a generator.
+func live(created, finished, leaked uint64) uint64 {
+	if finished+leaked > created {
+		return 0
+	}
+	return created - finished - leaked
+}
+
+// ExpvarSnapshot is the state of an `ExpvarMetrics` at some point.
+type ExpvarSnapshot struct {
+	Created      uint64            `json:"created"`
+	Live         uint64            `json:"live"`
+	Finished     uint64            `json:"finished"`
+	Leaked       uint64            `json:"leaked"`
+	Yields       uint64            `json:"yields"`
+	Errors       uint64            `json:"errors"`
+	ConsumerWait HistogramSnapshot `json:"consumer_wait"`
+	ProducerWait HistogramSnapshot `json:"producer_wait"`
+
+	// Generators maps the IDs of the live generators to their own
+	// measurements.
+	Generators map[string]GeneratorSnapshot `json:"generators"`
+}
+
+// GeneratorSnapshot is the state of the measurements of a generator.
+type GeneratorSnapshot struct {
+	Yields       uint64            `json:"yields"`
+	Errors       uint64            `json:"errors"`
+	ConsumerWait HistogramSnapshot `json:"consumer_wait"`
+	ProducerWait HistogramSnapshot `json:"producer_wait"`
+}
+
+// Snapshot returns the current state of the metrics.
+func (m *ExpvarMetrics) Snapshot() ExpvarSnapshot {
+	finished := atomic.LoadUint64(&m.finished)
+	leaked := atomic.LoadUint64(&m.leaked)
+	s := ExpvarSnapshot{
+		Created:      atomic.LoadUint64(&m.created),
+		Finished:     finished,
+		Leaked:       leaked,
+		Yields:       atomic.LoadUint64(&m.yields),
+		Errors:       atomic.LoadUint64(&m.errors),
+		ConsumerWait: m.consumerWait.Snapshot(),
+		ProducerWait: m.producerWait.Snapshot(),
+		Generators:   make(map[string]GeneratorSnapshot),
+	}
+	s.Live = live(s.Created, s.Finished, s.Leaked)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, gm := range m.generators {
+		s.Generators[strconv.FormatUint(id, 10)] = GeneratorSnapshot{
+			Yields:       atomic.LoadUint64(&gm.yields),
+			Errors:       atomic.LoadUint64(&gm.errors),
+			ConsumerWait: gm.consumerWait.Snapshot(),
+			ProducerWait: gm.producerWait.Snapshot(),
+		}
+	}
+	return s
+}
+
+// String returns the snapshot of the metrics encoded as JSON.
+func (m *ExpvarMetrics) String() string {
+	b, _ := json.Marshal(m.Snapshot())
+	return string(b)
+}
+
+func (m *ExpvarMetrics) get(id uint64) *generatorMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.generators[id]
+}
+
+func (m *ExpvarMetrics) drop(id uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.generators, id)
+}
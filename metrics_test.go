@@ -0,0 +1,216 @@
+package generator_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/generator"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run(`counts`, func(t *testing.T) {
+		m := generator.NewExpvarMetrics()
+		e1 := fmt.Errorf("e1")
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				gc.Yield(1)
+				gc.Yield(2)
+				gc.Error(e1)
+				return nil, nil
+			},
+			generator.WithMetrics(m),
+		)
+
+		g.Next(nil)
+		s := m.Snapshot()
+		if s.Created != 1 || s.Live != 1 || s.Yields != 1 {
+			t.Fatalf("got: %v, %v, %v. wanted: 1, 1, 1", s.Created, s.Live, s.Yields)
+		}
+		gs, ok := s.Generators[strconv.FormatUint(g.ID(), 10)]
+		if !ok || gs.Yields != 1 || gs.ConsumerWait.Count != 1 {
+			t.Fatalf("got: %v, %v, %v. wanted: true, 1, 1", ok, gs.Yields, gs.ConsumerWait.Count)
+		}
+
+		for {
+			if _, isDone, _ := g.Next(nil); isDone {
+				break
+			}
+		}
+		s = m.Snapshot()
+		if s.Created != 1 || s.Live != 0 || s.Finished != 1 || s.Leaked != 0 {
+			t.Fatalf("got: %v, %v, %v, %v. wanted: 1, 0, 1, 0", s.Created, s.Live, s.Finished, s.Leaked)
+		}
+		if s.Yields != 2 || s.Errors != 1 {
+			t.Fatalf("got: %v, %v. wanted: 2, 1", s.Yields, s.Errors)
+		}
+		if s.ConsumerWait.Count != 4 || s.ProducerWait.Count != 3 {
+			t.Fatalf("got: %v, %v. wanted: 4, 3", s.ConsumerWait.Count, s.ProducerWait.Count)
+		}
+		if len(s.Generators) != 0 {
+			t.Fatalf("got: %v. wanted: no generators", s.Generators)
+		}
+	})
+	t.Run(`returned error`, func(t *testing.T) {
+		m := generator.NewExpvarMetrics()
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				return nil, fmt.Errorf("e1")
+			},
+			generator.WithMetrics(m),
+		)
+		g.Next(nil)
+		if s := m.Snapshot(); s.Errors != 1 || s.Finished != 1 {
+			t.Fatalf("got: %v, %v. wanted: 1, 1", s.Errors, s.Finished)
+		}
+	})
+	t.Run(`SetDefaultMetrics`, func(t *testing.T) {
+		m := generator.NewExpvarMetrics()
+		generator.SetDefaultMetrics(m)
+		g1 := generator.New(func(gc *generator.Controller) (interface{}, error) { return nil, nil })
+		generator.SetDefaultMetrics(nil)
+		g2 := generator.New(func(gc *generator.Controller) (interface{}, error) { return nil, nil })
+		g1.Next(nil)
+		g2.Next(nil)
+
+		if s := m.Snapshot(); s.Created != 1 || s.Finished != 1 {
+			t.Fatalf("got: %v, %v. wanted: 1, 1", s.Created, s.Finished)
+		}
+	})
+	t.Run(`leaked`, func(t *testing.T) {
+		m := generator.NewExpvarMetrics()
+		func() {
+			g := generator.New(
+				func(gc *generator.Controller) (interface{}, error) {
+					gc.Yield(1)
+					return nil, nil
+				},
+				generator.WithMetrics(m),
+			)
+			g.Next(nil)
+		}()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for m.Snapshot().Leaked == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("the abandoned generator wasn't reported as leaked")
+			}
+			runtime.GC()
+			time.Sleep(time.Millisecond)
+		}
+		if s := m.Snapshot(); s.Live != 0 || s.Finished != 0 || len(s.Generators) != 0 {
+			t.Fatalf("got: %v, %v, %v. wanted: 0, 0, no generators", s.Live, s.Finished, s.Generators)
+		}
+	})
+	t.Run(`leaked while yielding with a context`, func(t *testing.T) {
+		m := &erroredMetrics{ExpvarMetrics: generator.NewExpvarMetrics(), errored: make(chan struct{})}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		func() {
+			g := generator.New(
+				func(gc *generator.Controller) (interface{}, error) {
+					_, _, err := gc.YieldContext(ctx, 1)
+					return nil, err
+				},
+				generator.WithMetrics(m),
+			)
+			g.Next(nil)
+		}()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for m.Snapshot().Leaked == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("the abandoned generator wasn't reported as leaked")
+			}
+			runtime.GC()
+			time.Sleep(time.Millisecond)
+		}
+
+		// the `Func` returns after the generator was counted as leaked
+		cancel()
+		<-m.errored
+		s := m.Snapshot()
+		if s.Created != 1 || s.Finished != 0 || s.Leaked != 1 || s.Live != 0 {
+			t.Fatalf("got: %v, %v, %v, %v. wanted: 1, 0, 1, 0", s.Created, s.Finished, s.Leaked, s.Live)
+		}
+	})
+	t.Run(`expvar`, func(t *testing.T) {
+		m := generator.NewExpvarMetrics()
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				gc.Yield(1)
+				return nil, nil
+			},
+			generator.WithMetrics(m),
+		)
+		g.Next(nil)
+
+		var v struct {
+			Created    uint64 `json:"created"`
+			Live       uint64 `json:"live"`
+			Yields     uint64 `json:"yields"`
+			Generators map[string]struct {
+				Yields       uint64 `json:"yields"`
+				ConsumerWait struct {
+					Count uint64 `json:"count"`
+				} `json:"consumer_wait"`
+			} `json:"generators"`
+		}
+		if err := json.Unmarshal([]byte(m.String()), &v); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		gv := v.Generators[strconv.FormatUint(g.ID(), 10)]
+		if v.Created != 1 || v.Live != 1 || v.Yields != 1 || gv.Yields != 1 || gv.ConsumerWait.Count != 1 {
+			t.Fatalf("got: %v", m.String())
+		}
+		g.Next(nil)
+	})
+}
+
+// erroredMetrics closes errored when a generator is counted as errored,
+// which happens after it's counted as finished.
+type erroredMetrics struct {
+	*generator.ExpvarMetrics
+	errored chan struct{}
+}
+
+func (m *erroredMetrics) Errored(id uint64) {
+	m.ExpvarMetrics.Errored(id)
+	close(m.errored)
+}
+
+func TestExpvarMetrics_Live(t *testing.T) {
+	m := generator.NewExpvarMetrics()
+	m.Created(1)
+	m.Finished(1)
+	m.Leaked(2)
+	if live, s := m.Live(), m.Snapshot(); live != 0 || s.Live != 0 {
+		t.Fatalf("got: %v, %v. wanted: 0, 0", live, s.Live)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var h generator.Histogram
+	h.Observe(500 * time.Nanosecond)
+	h.Observe(time.Microsecond)
+	h.Observe(5 * time.Millisecond)
+	h.Observe(time.Minute)
+
+	s := h.Snapshot()
+	if s.Count != 4 || s.SumNs != int64(time.Minute+5*time.Millisecond+1500*time.Nanosecond) {
+		t.Fatalf("got: %v, %v", s.Count, s.SumNs)
+	}
+	want := map[string]uint64{"1µs": 2, "10ms": 1, "+Inf": 1}
+	for k, c := range s.Buckets {
+		if c != want[k] {
+			t.Fatalf("got: %v for %v. wanted: %v", c, k, want[k])
+		}
+	}
+	if len(s.Buckets) != 9 {
+		t.Fatalf("got: %v buckets. wanted: 9", len(s.Buckets))
+	}
+}
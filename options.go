@@ -0,0 +1,59 @@
+package generator
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+)
+
+// Option configures a generator created by `New`.
+type Option func(g *Generator)
+
+// WithName names the generator. The name is shown by `DumpLive`, and
+// it's set as the "generator" pprof label of the goroutine of the
+// `Func`. See `WithLabels`.
+func WithName(name string) Option {
+	return func(g *Generator) {
+		g.name = name
+	}
+}
+
+// WithLabels adds pprof labels to the goroutine of the `Func`, and to
+// the goroutines it starts, so that CPU and goroutine profiles can tell
+// the generators apart. The labels are given as key/value pairs like in
+// `pprof.Labels`; it panics if there's an odd number of strings. The
+// labels are also shown by `DumpLive`.
+func WithLabels(labels ...string) Option {
+	if len(labels)%2 != 0 {
+		panic("generator: WithLabels: odd number of strings")
+	}
+	return func(g *Generator) {
+		g.labels = append(g.labels, labels...)
+	}
+}
+
+// WithTracer makes the generator report what it's doing to the tracer.
+// See `Tracer`.
+func WithTracer(tracer Tracer) Option {
+	return func(g *Generator) {
+		if _, ok := tracer.(NopTracer); ok {
+			tracer = nil
+		}
+		g.tracer = tracer
+	}
+}
+
+// setLabels sets the pprof labels of the goroutine of the `Func` if the
+// generator has a name or labels. The ID of the generator is added as
+// the "generator_id" label.
+func (g *state) setLabels() {
+	if g.name == "" && len(g.labels) == 0 {
+		return
+	}
+	labels := append([]string{"generator_id", strconv.FormatUint(g.id, 10)}, g.labels...)
+	if g.name != "" {
+		labels = append(labels, "generator", g.name)
+	}
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels(labels...))
+	pprof.SetGoroutineLabels(ctx)
+}
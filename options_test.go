@@ -0,0 +1,82 @@
+package generator_test
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/pprof"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+)
+
+func TestLabels(t *testing.T) {
+	t.Run(`WithName,WithLabels`, func(t *testing.T) {
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				gc.Yield(1)
+				return nil, nil
+			},
+			generator.WithName("numbers"),
+			generator.WithLabels("tenant", "t1"),
+		)
+		g.Next(nil)
+		defer g.Return(nil)
+
+		var b bytes.Buffer
+		pprof.Lookup("goroutine").WriteTo(&b, 1)
+		for _, want := range []string{
+			`"generator":"numbers"`,
+			fmt.Sprintf(`"generator_id":"%d"`, g.ID()),
+			`"tenant":"t1"`,
+		} {
+			if !strings.Contains(b.String(), want) {
+				t.Fatalf("wanted the goroutine profile to contain: %v", want)
+			}
+		}
+	})
+	t.Run(`inherited`, func(t *testing.T) {
+		done := make(chan struct{})
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				go func() {
+					<-done
+				}()
+				gc.Yield(1)
+				return nil, nil
+			},
+			generator.WithLabels("job", "inherited-label"),
+		)
+		g.Next(nil)
+		defer g.Return(nil)
+		defer close(done)
+
+		var b bytes.Buffer
+		pprof.Lookup("goroutine").WriteTo(&b, 1)
+		if n := strings.Count(b.String(), `"job":"inherited-label"`); n != 2 {
+			t.Fatalf("got: %v goroutines with the label. wanted: 2", n)
+		}
+	})
+	t.Run(`odd number of strings`, func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("wanted a panic")
+			}
+		}()
+		generator.WithLabels("tenant")
+	})
+	t.Run(`LiveGenerators`, func(t *testing.T) {
+		generator.SetRegistryEnabled(true)
+		defer generator.SetRegistryEnabled(false)
+
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) { return nil, nil },
+			generator.WithLabels("tenant", "t1"),
+		)
+		defer g.Next(nil)
+
+		if lg := findLive(g.ID()); lg == nil || lg.Labels["tenant"] != "t1" {
+			t.Fatalf("got: %+v. wanted: the labels", lg)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+// Package paginate creates generators that yield the items of paginated
+// APIs, fetching a page only when the consumer has received all of the
+// items of the previous one.
+package paginate
+
+import (
+	"context"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/retry"
+)
+
+// FetchPageFunc fetches the page identified by token. The first page is
+// identified by an empty token. It returns the items of the page and the
+// token of the next page, which is empty if it's the last page.
+type FetchPageFunc func(ctx context.Context, token string) (items []interface{}, nextToken string, err error)
+
+// Paginate creates a generator that yields the items of the pages
+// fetched by fetch one item at a time. A page is only fetched after the
+// consumer has received all of the items of the previous page, and no
+// more pages are fetched after the consumer calls `Generator#Return`,
+// which makes the `Func` return the value passed to it.
+//
+// If fetch fails, it's retried according to policy; a nil policy means
+// that it's never retried. When it can't be retried anymore, the
+// generator is stopped and delivers the error. The generator is also
+// stopped when ctx is done while waiting before a retry, or when the
+// consumer calls `Generator#Error`.
+func Paginate(ctx context.Context, fetch FetchPageFunc, policy *retry.Policy) *generator.Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			token := ""
+			for {
+				items, nextToken, err := fetchPage(ctx, fetch, policy, token)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, item := range items {
+					value, shouldReturn, err := gc.Yield(item)
+					if shouldReturn {
+						return value, nil
+					} else if err != nil {
+						return nil, err
+					}
+				}
+
+				if nextToken == "" {
+					return nil, nil
+				}
+				token = nextToken
+			}
+		},
+	)
+}
+
+func fetchPage(
+	ctx context.Context,
+	fetch FetchPageFunc,
+	policy *retry.Policy,
+	token string,
+) ([]interface{}, string, error) {
+	for attempt := 1; ; attempt++ {
+		items, nextToken, err := fetch(ctx, token)
+		if err == nil {
+			return items, nextToken, nil
+		}
+		if !policy.ShouldRetry(attempt, err) {
+			return nil, "", err
+		}
+		if werr := policy.Wait(ctx, attempt); werr != nil {
+			return nil, "", werr
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package paginate_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/paginate"
+	"github.com/bmdelacruz/generator/retry"
+)
+
+func TestPaginate(t *testing.T) {
+	pages := map[string]page{
+		"":   {Items: []int{1, 2}, Next: "p2"},
+		"p2": {Items: []int{3}, Next: "p3"},
+		"p3": {Items: []int{4, 5}},
+	}
+
+	t.Run(`all items`, func(t *testing.T) {
+		api := newPageServer(pages, 0)
+		defer api.Close()
+
+		g := paginate.Paginate(context.Background(), api.fetch, nil)
+		got, err := drainAll(g)
+		want := []interface{}{1, 2, 3, 4, 5}
+		if !reflect.DeepEqual(got, want) || err != nil {
+			t.Fatalf("got: %v, %v. wanted: %v, <nil>", got, err, want)
+		}
+		if want := []string{"", "p2", "p3"}; !reflect.DeepEqual(api.requested(), want) {
+			t.Fatalf("got: %v. wanted: %v", api.requested(), want)
+		}
+	})
+	t.Run(`lazy fetching and Return("r")`, func(t *testing.T) {
+		api := newPageServer(pages, 0)
+		defer api.Close()
+
+		g := paginate.Paginate(context.Background(), api.fetch, nil)
+		testWith(t).expect(g.Next(nil)).toReturn(1, false, nil)
+		testWith(t).expect(g.Next(nil)).toReturn(2, false, nil)
+		if want := []string{""}; !reflect.DeepEqual(api.requested(), want) {
+			t.Fatalf("got: %v. wanted: %v", api.requested(), want)
+		}
+		testWith(t).expect(g.Next(nil)).toReturn(3, false, nil)
+		testWith(t).expect(g.Return("r")).toReturn("r", true, nil)
+		if want := []string{"", "p2"}; !reflect.DeepEqual(api.requested(), want) {
+			t.Fatalf("got: %v. wanted: %v", api.requested(), want)
+		}
+	})
+	t.Run(`retries transient errors`, func(t *testing.T) {
+		api := newPageServer(pages, 2)
+		defer api.Close()
+
+		policy := &retry.Policy{MaxAttempts: 3}
+		g := paginate.Paginate(context.Background(), api.fetch, policy)
+		got, err := drainAll(g)
+		want := []interface{}{1, 2, 3, 4, 5}
+		if !reflect.DeepEqual(got, want) || err != nil {
+			t.Fatalf("got: %v, %v. wanted: %v, <nil>", got, err, want)
+		}
+		if want := []string{"", "", "", "p2", "p3"}; !reflect.DeepEqual(api.requested(), want) {
+			t.Fatalf("got: %v. wanted: %v", api.requested(), want)
+		}
+	})
+	t.Run(`gives up after max attempts`, func(t *testing.T) {
+		api := newPageServer(pages, 2)
+		defer api.Close()
+
+		policy := &retry.Policy{MaxAttempts: 2}
+		g := paginate.Paginate(context.Background(), api.fetch, policy)
+		_, isDone, err := g.Next(nil)
+		if !isDone || !errors.Is(err, errUnavailable) {
+			t.Fatalf("got: %v, %v. wanted: true, %v", isDone, err, errUnavailable)
+		}
+	})
+	t.Run(`non-retryable error`, func(t *testing.T) {
+		api := newPageServer(pages, 1)
+		defer api.Close()
+
+		policy := &retry.Policy{
+			MaxAttempts: 5,
+			Retryable:   func(err error) bool { return false },
+		}
+		g := paginate.Paginate(context.Background(), api.fetch, policy)
+		_, isDone, err := g.Next(nil)
+		if !isDone || !errors.Is(err, errUnavailable) {
+			t.Fatalf("got: %v, %v. wanted: true, %v", isDone, err, errUnavailable)
+		}
+		if n := len(api.requested()); n != 1 {
+			t.Fatalf("got: %v. wanted: 1", n)
+		}
+	})
+}
+
+type page struct {
+	Items []int  `json:"items"`
+	Next  string `json:"next"`
+}
+
+var errUnavailable = errors.New("service unavailable")
+
+// pageServer is a stand-in for a paginated API. It fails the first
+// failures requests with a 503. The tokens of all of the requests are
+// recorded.
+type pageServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	tokens   []string
+	failures int
+}
+
+func newPageServer(pages map[string]page, failures int) *pageServer {
+	ps := &pageServer{failures: failures}
+	ps.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+
+		token := r.URL.Query().Get("token")
+		ps.tokens = append(ps.tokens, token)
+
+		if ps.failures > 0 {
+			ps.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(pages[token])
+	}))
+	return ps
+}
+
+func (ps *pageServer) fetch(ctx context.Context, token string) ([]interface{}, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", ps.URL+"?token="+token, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	res, err := ps.Client().Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusServiceUnavailable {
+		return nil, "", fmt.Errorf("fetch %q: %w", token, errUnavailable)
+	}
+	var p page
+	if err := json.NewDecoder(res.Body).Decode(&p); err != nil {
+		return nil, "", err
+	}
+	items := make([]interface{}, len(p.Items))
+	for i, item := range p.Items {
+		items[i] = item
+	}
+	return items, p.Next, nil
+}
+
+// requested returns the tokens of the pages that were requested.
+func (ps *pageServer) requested() []string {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return append([]string{}, ps.tokens...)
+}
+
+// drainAll calls `Next` until g is done and returns the yielded values.
+// It returns the first error that was received.
+func drainAll(g *generator.Generator) ([]interface{}, error) {
+	var values []interface{}
+	for {
+		v, isDone, err := g.Next(nil)
+		if err != nil {
+			return values, err
+		}
+		if isDone {
+			return values, nil
+		}
+		values = append(values, v)
+	}
+}
+
+// utility stuff =====================================================
+
+type tw struct {
+	t *testing.T
+}
+
+type twe struct {
+	tw *tw
+	v  interface{}
+	r  bool
+	e  error
+}
+
+func testWith(t *testing.T) *tw {
+	return &tw{t}
+}
+
+func (tw *tw) expect(v interface{}, r bool, e error) *twe {
+	return &twe{tw, v, r, e}
+}
+
+func (twe *twe) toReturn(v interface{}, r bool, e error) {
+	if v != twe.v || r != twe.r || e != twe.e {
+		twe.tw.t.Helper()
+		twe.tw.t.Fatalf(
+			"got: %v, %v, %v. wanted: %v, %v, %v",
+			twe.v, twe.r, twe.e, v, r, e,
+		)
+	}
+}
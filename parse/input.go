@@ -0,0 +1,143 @@
+// Package parse provides parser combinators that consume the tokens of
+// a generator created by `lex.Run`.
+//
+// The tokens are pulled from the generator lazily and buffered by an
+// `Input` so that a parser can rewind to a checkpoint and try another
+// alternative.
+package parse
+
+import (
+	"fmt"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/lex"
+)
+
+// Error is a parse error at the position of the offending token.
+type Error struct {
+	Pos lex.Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %s", e.Pos, e.Msg)
+}
+
+// Input is a buffered view of a token generator. All of the tokens that
+// have been pulled from the generator are kept so that the input can be
+// rewound to any checkpoint.
+type Input struct {
+	g   *generator.Generator
+	buf []lex.Token
+	pos int
+
+	// done is set when the generator is done. err is the error that was
+	// delivered by the generator, if any.
+	done bool
+	err  error
+
+	// furthest is the parse error that is the furthest into the input.
+	// it's reported instead of a less specific error when a combinator
+	// like `Many` has backtracked over it.
+	furthest *Error
+}
+
+// NewInput creates an input that pulls the tokens from g.
+func NewInput(g *generator.Generator) *Input {
+	return &Input{g: g}
+}
+
+// Peek returns the next token without consuming it. It returns false if
+// there are no more tokens, and the generator's error if it delivered
+// one instead of a token.
+func (in *Input) Peek() (lex.Token, bool, error) {
+	if in.pos == len(in.buf) && !in.done {
+		in.fill()
+	}
+	if in.pos < len(in.buf) {
+		return in.buf[in.pos], true, nil
+	}
+	return lex.Token{}, false, in.err
+}
+
+// Next returns the next token and consumes it. It returns the same
+// values as `Peek`.
+func (in *Input) Next() (lex.Token, bool, error) {
+	token, ok, err := in.Peek()
+	if ok {
+		in.pos++
+	}
+	return token, ok, err
+}
+
+// Checkpoint returns the current position of the input.
+func (in *Input) Checkpoint() int {
+	return in.pos
+}
+
+// Rewind moves the input back to the checkpoint.
+func (in *Input) Rewind(checkpoint int) {
+	in.pos = checkpoint
+}
+
+// Pos returns the position of the next token, or the position right
+// after the last token if there are no more tokens.
+func (in *Input) Pos() lex.Pos {
+	if token, ok, _ := in.Peek(); ok {
+		return token.Pos
+	}
+	if len(in.buf) == 0 {
+		return lex.Pos{Line: 1, Column: 1}
+	}
+	return end(in.buf[len(in.buf)-1])
+}
+
+// Close stops the generator if it isn't done yet.
+func (in *Input) Close() {
+	if !in.done {
+		in.done = true
+		in.g.Return(nil)
+	}
+}
+
+// fail records the parse error if it's the furthest one so far and
+// returns it.
+func (in *Input) fail(err *Error) *Error {
+	if in.furthest == nil || err.Pos.Offset > in.furthest.Pos.Offset {
+		in.furthest = err
+	}
+	return err
+}
+
+// fill pulls the next token from the generator. The generator's error
+// ends the input since the lexer can't be resumed reliably after it.
+func (in *Input) fill() {
+	value, isDone, err := in.g.Next(nil)
+	switch {
+	case err != nil:
+		in.err = err
+		if !isDone {
+			in.g.Return(nil)
+		}
+		in.done = true
+	case isDone:
+		in.done = true
+	default:
+		in.buf = append(in.buf, value.(lex.Token))
+	}
+}
+
+// end returns the position right after the token.
+func end(token lex.Token) lex.Pos {
+	p := token.Pos
+	for _, r := range token.Value {
+		if r == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	p.Offset += len(token.Value)
+	return p
+}
@@ -0,0 +1,196 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/lex"
+)
+
+// Parser parses a value from the input. A parser that fails with an
+// `*Error` may have consumed tokens; the combinators rewind the input
+// when they try another alternative. Any other error, like the error of
+// the lexer, stops the parsing.
+type Parser func(in *Input) (interface{}, error)
+
+// Parse parses the tokens of g using p and checks that all of the
+// tokens were consumed. If they weren't, the parse error that is the
+// furthest into the input is returned, since it's usually the reason
+// why p stopped early. The generator is stopped with
+// `Generator#Return` if it's not done when the parsing ends.
+func Parse(g *generator.Generator, p Parser) (interface{}, error) {
+	in := NewInput(g)
+	defer in.Close()
+
+	value, err := p(in)
+	if err != nil {
+		return nil, err
+	}
+	token, ok, err := in.Peek()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if in.furthest != nil && in.furthest.Pos.Offset >= token.Pos.Offset {
+			return nil, in.furthest
+		}
+		return nil, &Error{Pos: token.Pos, Msg: fmt.Sprintf("unexpected %q, expected end of input", token.Value)}
+	}
+	return value, nil
+}
+
+// Expect parses a token of the type t and returns the `lex.Token`.
+func Expect(t lex.Type) Parser {
+	return func(in *Input) (interface{}, error) {
+		return expect(in, t, func(token lex.Token) bool {
+			return true
+		}, fmt.Sprintf("token of type %d", t))
+	}
+}
+
+// ExpectValue parses a token of the type t whose value is value and
+// returns the `lex.Token`.
+func ExpectValue(t lex.Type, value string) Parser {
+	return func(in *Input) (interface{}, error) {
+		return expect(in, t, func(token lex.Token) bool {
+			return token.Value == value
+		}, fmt.Sprintf("%q", value))
+	}
+}
+
+func expect(in *Input, t lex.Type, match func(lex.Token) bool, expected string) (interface{}, error) {
+	pos := in.Pos()
+	token, ok, err := in.Next()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, in.fail(&Error{Pos: pos, Msg: fmt.Sprintf("unexpected end of input, expected %s", expected)})
+	}
+	if token.Type != t || !match(token) {
+		return nil, in.fail(&Error{Pos: token.Pos, Msg: fmt.Sprintf("unexpected %q, expected %s", token.Value, expected)})
+	}
+	return token, nil
+}
+
+// Seq parses each of the parsers in order and returns a `[]interface{}`
+// of their values.
+func Seq(parsers ...Parser) Parser {
+	return func(in *Input) (interface{}, error) {
+		values := make([]interface{}, len(parsers))
+		for i, p := range parsers {
+			value, err := p(in)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+}
+
+// Alt tries each of the parsers in order from the same position and
+// returns the value of the first one that succeeds. If all of them fail,
+// it returns the error that is the furthest into the input.
+func Alt(parsers ...Parser) Parser {
+	return func(in *Input) (interface{}, error) {
+		checkpoint := in.Checkpoint()
+
+		var furthest *Error
+		for _, p := range parsers {
+			value, err := p(in)
+			if err == nil {
+				return value, nil
+			}
+
+			var perr *Error
+			if !errors.As(err, &perr) {
+				return nil, err
+			}
+			if furthest == nil || perr.Pos.Offset > furthest.Pos.Offset {
+				furthest = perr
+			}
+			in.Rewind(checkpoint)
+		}
+		if furthest == nil {
+			return nil, &Error{Pos: in.Pos(), Msg: "no alternatives"}
+		}
+		return nil, furthest
+	}
+}
+
+// Many parses p as many times as possible and returns a `[]interface{}`
+// of the values. It stops when p fails, rewinding the input to where
+// the failed attempt started, or when p succeeds without consuming any
+// token.
+func Many(p Parser) Parser {
+	return func(in *Input) (interface{}, error) {
+		var values []interface{}
+		for {
+			checkpoint := in.Checkpoint()
+			value, err := p(in)
+			if err != nil {
+				var perr *Error
+				if !errors.As(err, &perr) {
+					return nil, err
+				}
+				in.Rewind(checkpoint)
+				return values, nil
+			}
+			values = append(values, value)
+			if in.Checkpoint() == checkpoint {
+				return values, nil
+			}
+		}
+	}
+}
+
+// Optional parses p and returns its value, or returns nil and rewinds
+// the input if p fails.
+func Optional(p Parser) Parser {
+	return func(in *Input) (interface{}, error) {
+		checkpoint := in.Checkpoint()
+		value, err := p(in)
+		if err != nil {
+			var perr *Error
+			if !errors.As(err, &perr) {
+				return nil, err
+			}
+			in.Rewind(checkpoint)
+			return nil, nil
+		}
+		return value, nil
+	}
+}
+
+// Map parses p and returns the result of calling fn with its value. An
+// error returned by fn is reported as an `*Error` at the position where
+// p started, unless it's already an `*Error`.
+func Map(p Parser, fn func(value interface{}) (interface{}, error)) Parser {
+	return func(in *Input) (interface{}, error) {
+		pos := in.Pos()
+		value, err := p(in)
+		if err != nil {
+			return nil, err
+		}
+		mapped, err := fn(value)
+		if err != nil {
+			var perr *Error
+			if !errors.As(err, &perr) {
+				err = in.fail(&Error{Pos: pos, Msg: err.Error()})
+			}
+			return nil, err
+		}
+		return mapped, nil
+	}
+}
+
+// Lazy returns a parser that calls fn to get the parser to use. It
+// allows recursive grammars to refer to parsers that are not defined
+// yet.
+func Lazy(fn func() Parser) Parser {
+	return func(in *Input) (interface{}, error) {
+		return fn()(in)
+	}
+}
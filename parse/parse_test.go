@@ -0,0 +1,156 @@
+package parse_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/lex"
+	"github.com/bmdelacruz/generator/parse"
+)
+
+const (
+	Number lex.Type = iota
+	Ident
+	Punct
+)
+
+func lexAny(l *lex.Lexer) lex.StateFn {
+	switch r := l.Peek(); {
+	case r == lex.EOF:
+		return nil
+	case r == ' ':
+		l.AcceptRun(" ")
+		l.Ignore()
+	case l.AcceptRun("0123456789") > 0:
+		l.Emit(Number)
+	case l.AcceptRun("abcdefghijklmnopqrstuvwxyz") > 0:
+		l.Emit(Ident)
+	case l.Accept("+-()=;"):
+		l.Emit(Punct)
+	default:
+		l.Next()
+		return l.Errorf("unexpected %q", r)
+	}
+	return lexAny
+}
+
+// expr = term { ("+" | "-") term }
+// term = number | "(" expr ")"
+var expr parse.Parser
+
+func init() {
+	number := parse.Map(parse.Expect(Number), func(v interface{}) (interface{}, error) {
+		return strconv.Atoi(v.(lex.Token).Value)
+	})
+	term := parse.Alt(
+		number,
+		parse.Map(
+			parse.Seq(parse.ExpectValue(Punct, "("), parse.Lazy(func() parse.Parser { return expr }), parse.ExpectValue(Punct, ")")),
+			func(v interface{}) (interface{}, error) {
+				return v.([]interface{})[1], nil
+			},
+		),
+	)
+	op := parse.Alt(parse.ExpectValue(Punct, "+"), parse.ExpectValue(Punct, "-"))
+	expr = parse.Map(
+		parse.Seq(term, parse.Many(parse.Seq(op, term))),
+		func(v interface{}) (interface{}, error) {
+			values := v.([]interface{})
+			sum := values[0].(int)
+			for _, rest := range values[1].([]interface{}) {
+				pair := rest.([]interface{})
+				if pair[0].(lex.Token).Value == "+" {
+					sum += pair[1].(int)
+				} else {
+					sum -= pair[1].(int)
+				}
+			}
+			return sum, nil
+		},
+	)
+}
+
+func TestParse(t *testing.T) {
+	t.Run(`expression`, func(t *testing.T) {
+		v, err := parse.Parse(lex.Run("1 + (10 - 4) - 2", lexAny), expr)
+		if v != 5 || err != nil {
+			t.Fatalf("got: %v, %v. wanted: 5, <nil>", v, err)
+		}
+	})
+	t.Run(`error position`, func(t *testing.T) {
+		_, err := parse.Parse(lex.Run("1 + (2 - )", lexAny), expr)
+		var perr *parse.Error
+		if !errors.As(err, &perr) || perr.Pos != (lex.Pos{Offset: 9, Line: 1, Column: 10}) {
+			t.Fatalf("got: %v. wanted: an error at 1:10", err)
+		}
+	})
+	t.Run(`unexpected end of input`, func(t *testing.T) {
+		_, err := parse.Parse(lex.Run("1 +", lexAny), expr)
+		var perr *parse.Error
+		if !errors.As(err, &perr) || perr.Pos != (lex.Pos{Offset: 3, Line: 1, Column: 4}) {
+			t.Fatalf("got: %v. wanted: an error at 1:4", err)
+		}
+	})
+	t.Run(`trailing tokens`, func(t *testing.T) {
+		_, err := parse.Parse(lex.Run("1 2", lexAny), expr)
+		var perr *parse.Error
+		if !errors.As(err, &perr) || perr.Pos.Offset != 2 {
+			t.Fatalf("got: %v. wanted: an error at offset 2", err)
+		}
+	})
+	t.Run(`lexer error`, func(t *testing.T) {
+		_, err := parse.Parse(lex.Run("1 + $", lexAny), expr)
+		var lexErr *lex.Error
+		if !errors.As(err, &lexErr) {
+			t.Fatalf("got: %v. wanted: a lexer error", err)
+		}
+	})
+}
+
+func TestAlt_Backtracking(t *testing.T) {
+	// both alternatives start with an identifier
+	assign := parse.Map(
+		parse.Seq(parse.Expect(Ident), parse.ExpectValue(Punct, "="), parse.Expect(Number)),
+		func(interface{}) (interface{}, error) { return "assign", nil },
+	)
+	call := parse.Map(
+		parse.Seq(parse.Expect(Ident), parse.ExpectValue(Punct, "("), parse.ExpectValue(Punct, ")")),
+		func(interface{}) (interface{}, error) { return "call", nil },
+	)
+	stmt := parse.Seq(parse.Alt(assign, call), parse.Optional(parse.ExpectValue(Punct, ";")))
+
+	for input, want := range map[string]string{"x = 1;": "assign", "f()": "call"} {
+		v, err := parse.Parse(lex.Run(input, lexAny), stmt)
+		if err != nil || v.([]interface{})[0] != want {
+			t.Fatalf("%q: got: %v, %v. wanted: %v, <nil>", input, v, err, want)
+		}
+	}
+
+	// the error of the alternative that got further is reported
+	_, err := parse.Parse(lex.Run("f(1", lexAny), stmt)
+	var perr *parse.Error
+	if !errors.As(err, &perr) || perr.Pos.Offset != 2 {
+		t.Fatalf("got: %v. wanted: an error at offset 2", err)
+	}
+}
+
+func TestParse_StopsGenerator(t *testing.T) {
+	stopped := false
+	g := generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			for {
+				_, shouldReturn, _ := gc.Yield(lex.Token{Type: Number, Value: "1"})
+				if shouldReturn {
+					stopped = true
+					return nil, nil
+				}
+			}
+		},
+	)
+	parse.Parse(g, parse.Expect(Ident))
+	if !stopped {
+		t.Fatal("the generator was not stopped")
+	}
+}
@@ -0,0 +1,118 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrReaderClosed is returned by `Reader#Read` and `Reader#WriteTo`
+// after the reader has been closed.
+var ErrReaderClosed = errors.New("generator: read from closed reader")
+
+// Reader reads the `[]byte` or `string` chunks yielded by a generator
+// as a stream of bytes. It implements `io.Reader`, `io.WriterTo` and
+// `io.Closer`. It's not safe for concurrent use.
+type Reader struct {
+	g   *Generator
+	buf []byte
+
+	// err is the error that will be returned once buf has been read
+	err error
+}
+
+// NewReader creates a reader of the chunks yielded by g. The chunks are
+// pulled by calling `Generator#Next` with a nil value when the previous
+// chunk has been completely read.
+//
+// An error delivered by g and any chunk that is not a `[]byte` or a
+// `string` ends the stream with an error; g is stopped with
+// `Generator#Return` in that case. When g is done, the stream ends with
+// `io.EOF`, or with the error returned by the `Func` if it isn't nil.
+func NewReader(g *Generator) *Reader {
+	return &Reader{g: g}
+}
+
+// Read reads up to len(p) bytes into p. It only waits for the next
+// chunk if no bytes of the previous chunk are left to be read.
+func (r *Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.fill()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// WriteTo writes the remaining chunks to w until the stream ends. It
+// returns the number of bytes written and, unless the stream ended with
+// `io.EOF`, the error that ended it. If w returns an error, g is stopped
+// with `Generator#Return`.
+func (r *Reader) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for {
+		if len(r.buf) > 0 {
+			n, err := w.Write(r.buf)
+			written += int64(n)
+			r.buf = r.buf[n:]
+			if err == nil && len(r.buf) > 0 {
+				err = io.ErrShortWrite
+			}
+			if err != nil {
+				r.Close()
+				return written, err
+			}
+		}
+		if r.err == io.EOF {
+			return written, nil
+		} else if r.err != nil {
+			return written, r.err
+		}
+		r.fill()
+	}
+}
+
+// Close stops the generator with `Generator#Return` if it isn't done
+// yet. Reading after that returns `ErrReaderClosed`.
+func (r *Reader) Close() error {
+	if r.err == nil {
+		r.g.Return(nil)
+	}
+	r.buf = nil
+	r.err = ErrReaderClosed
+	return nil
+}
+
+// fill pulls the next chunk from the generator into buf or sets err if
+// the stream has ended.
+func (r *Reader) fill() {
+	value, isDone, err := r.g.Next(nil)
+	switch {
+	case isDone && err == nil:
+		r.err = io.EOF
+		return
+	case isDone:
+		r.err = err
+		return
+	case err != nil:
+		r.g.Return(nil)
+		r.err = err
+		return
+	}
+
+	switch chunk := value.(type) {
+	case []byte:
+		r.buf = chunk
+	case string:
+		r.buf = []byte(chunk)
+	default:
+		r.g.Return(nil)
+		r.err = fmt.Errorf("generator: can't read %T chunk", value)
+	}
+}
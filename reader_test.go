@@ -0,0 +1,131 @@
+package generator_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/bmdelacruz/generator"
+)
+
+func chunks(values ...interface{}) *generator.Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			for _, v := range values {
+				if err, ok := v.(error); ok {
+					gc.Error(err)
+					continue
+				}
+				if _, shouldReturn, _ := gc.Yield(v); shouldReturn {
+					return nil, nil
+				}
+			}
+			return nil, nil
+		},
+	)
+}
+
+func TestReader(t *testing.T) {
+	t.Run(`partial reads`, func(t *testing.T) {
+		r := generator.NewReader(chunks("hello", []byte(", "), "", "world"))
+		if err := iotest.TestReader(r, []byte("hello, world")); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run(`one byte at a time`, func(t *testing.T) {
+		r := generator.NewReader(chunks("ab", "c"))
+		b, err := io.ReadAll(iotest.OneByteReader(r))
+		if string(b) != "abc" || err != nil {
+			t.Fatalf("got: %q, %v. wanted: abc, <nil>", b, err)
+		}
+	})
+	t.Run(`producer error`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		r := generator.NewReader(chunks("ab", e1, "c"))
+		b, err := io.ReadAll(r)
+		if string(b) != "ab" || err != e1 {
+			t.Fatalf("got: %q, %v. wanted: ab, e1", b, err)
+		}
+	})
+	t.Run(`Func error`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		r := generator.NewReader(generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				gc.Yield("ab")
+				return nil, e1
+			},
+		))
+		b, err := io.ReadAll(r)
+		if string(b) != "ab" || err != e1 {
+			t.Fatalf("got: %q, %v. wanted: ab, e1", b, err)
+		}
+	})
+	t.Run(`unsupported chunk`, func(t *testing.T) {
+		r := generator.NewReader(chunks("ab", 1))
+		b, err := io.ReadAll(r)
+		if string(b) != "ab" || err == nil {
+			t.Fatalf("got: %q, %v. wanted: ab, an error", b, err)
+		}
+	})
+	t.Run(`Close()`, func(t *testing.T) {
+		stopped := false
+		r := generator.NewReader(generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				for {
+					if _, shouldReturn, _ := gc.Yield("x"); shouldReturn {
+						stopped = true
+						return nil, nil
+					}
+				}
+			},
+		))
+		r.Read(make([]byte, 1))
+		if err := r.Close(); err != nil || !stopped {
+			t.Fatalf("got: %v, %v. wanted: <nil>, true", err, stopped)
+		}
+		if _, err := r.Read(make([]byte, 1)); err != generator.ErrReaderClosed {
+			t.Fatalf("got: %v. wanted: %v", err, generator.ErrReaderClosed)
+		}
+	})
+}
+
+func TestReader_WriteTo(t *testing.T) {
+	t.Run(`chunks`, func(t *testing.T) {
+		r := generator.NewReader(chunks("hello", []byte(", "), "world"))
+		r.Read(make([]byte, 2))
+
+		var b bytes.Buffer
+		n, err := r.WriteTo(&b)
+		if b.String() != "llo, world" || n != 10 || err != nil {
+			t.Fatalf("got: %q, %v, %v. wanted: llo, world, 10, <nil>", b.String(), n, err)
+		}
+	})
+	t.Run(`write error`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		r := generator.NewReader(chunks("a", "b"))
+		if _, err := r.WriteTo(errWriter{e1}); err != e1 {
+			t.Fatalf("got: %v. wanted: e1", err)
+		}
+		if _, err := r.Read(make([]byte, 1)); err != generator.ErrReaderClosed {
+			t.Fatalf("got: %v. wanted: %v", err, generator.ErrReaderClosed)
+		}
+	})
+	t.Run(`io.Copy`, func(t *testing.T) {
+		var b strings.Builder
+		n, err := io.Copy(&b, generator.NewReader(chunks("a", "bc")))
+		if b.String() != "abc" || n != 3 || err != nil {
+			t.Fatalf("got: %q, %v, %v. wanted: abc, 3, <nil>", b.String(), n, err)
+		}
+	})
+}
+
+type errWriter struct {
+	err error
+}
+
+func (w errWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
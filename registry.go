@@ -0,0 +1,278 @@
+package generator
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// State is what a generator is doing at some point.
+type State int
+
+const (
+	// StateCreated is the state of a generator whose `Func` hasn't
+	// started because the consumer hasn't called any of the generator
+	// functions yet.
+	StateCreated State = iota
+
+	// StateRunning is the state of a generator whose `Func` is running,
+	// which means that its consumer is waiting for it.
+	StateRunning
+
+	// StateSuspended is the state of a generator whose `Func` is
+	// waiting in a controller function for its consumer.
+	StateSuspended
+)
+
+var stateNames = [...]string{
+	StateCreated:   "created",
+	StateRunning:   "running",
+	StateSuspended: "suspended",
+}
+
+// String returns the name of the state, like "suspended".
+func (s State) String() string {
+	if s < 0 || int(s) >= len(stateNames) {
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+	return stateNames[s]
+}
+
+// MarshalText encodes the state as its name.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// registry keeps the generators that are created while it's enabled
+// until their `Func` returns.
+var registry struct {
+	enabled int32
+
+	mu         sync.Mutex
+	generators map[uint64]*state
+}
+
+// SetRegistryEnabled sets whether the generators that are created after
+// it's called are added to the registry of live generators, which is
+// what `LiveGenerators` and `DumpLive` report and what the livehttp
+// package serves. It's disabled by default since it makes every
+// generator record the stack that created it and the location of every
+// controller function call.
+//
+// Generators that were added to the registry stay there until their
+// `Func` returns even after the registry is disabled.
+func SetRegistryEnabled(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&registry.enabled, v)
+}
+
+// debugInfo is what the registry knows about a generator.
+type debugInfo struct {
+	createdAt time.Time
+	creation  []uintptr
+
+	mu      sync.Mutex
+	state   State
+	since   time.Time
+	pending string
+	leaked  bool
+}
+
+// register adds the generator to the registry if it's enabled. skip is
+// the number of stack frames to skip to get to the caller of `New`.
+func (g *state) register(skip int) {
+	if atomic.LoadInt32(&registry.enabled) == 0 {
+		return
+	}
+
+	var pcs [32]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	now := time.Now()
+	g.debug = &debugInfo{
+		createdAt: now,
+		creation:  pcs[:n],
+		state:     StateCreated,
+		since:     now,
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if registry.generators == nil {
+		registry.generators = make(map[uint64]*state)
+	}
+	registry.generators[g.id] = g
+}
+
+func (g *state) unregister() {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	delete(registry.generators, g.id)
+}
+
+// setState records the state of a registered generator. pending is the
+// location of the controller function call the `Func` is suspended at.
+func (g *state) setState(s State, pending string) {
+	if g.debug == nil {
+		return
+	}
+	g.debug.mu.Lock()
+	defer g.debug.mu.Unlock()
+	g.debug.state = s
+	g.debug.since = time.Now()
+	g.debug.pending = pending
+}
+
+// callerOf returns the location of the caller of the controller
+// function of a registered generator, or "" if it isn't registered.
+func (g *state) callerOf() string {
+	if g.debug == nil {
+		return ""
+	}
+	// skip callerOf and the controller function
+	_, file, line, ok := runtime.Caller(2)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// LiveGenerator describes a generator in the registry.
+type LiveGenerator struct {
+	ID     uint64            `json:"id"`
+	Name   string            `json:"name,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+
+	State State `json:"state"`
+
+	// Since is when the generator entered its current state.
+	Since time.Time `json:"since"`
+
+	// PendingYield is the location, as "file:line", of the controller
+	// function call the `Func` is suspended at. It's empty if the
+	// generator isn't suspended.
+	PendingYield string `json:"pending_yield,omitempty"`
+
+	// Leaked is true if the generator was garbage collected while its
+	// `Func` was still running. Nothing can resume its `Func` unless
+	// the `Func` stops on its own.
+	Leaked bool `json:"leaked,omitempty"`
+
+	CreatedAt     time.Time `json:"created_at"`
+	CreationStack []Frame   `json:"creation_stack"`
+}
+
+// Frame is a frame of the stack that created a generator.
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// LiveGenerators returns the generators in the registry ordered by
+// their ID. See `SetRegistryEnabled`.
+func LiveGenerators() []LiveGenerator {
+	registry.mu.Lock()
+	states := make([]*state, 0, len(registry.generators))
+	for _, g := range registry.generators {
+		states = append(states, g)
+	}
+	registry.mu.Unlock()
+
+	sort.Slice(states, func(i, j int) bool { return states[i].id < states[j].id })
+
+	live := make([]LiveGenerator, 0, len(states))
+	for _, g := range states {
+		d := g.debug
+		d.mu.Lock()
+		lg := LiveGenerator{
+			ID:           g.id,
+			Name:         g.name,
+			State:        d.state,
+			Since:        d.since,
+			PendingYield: d.pending,
+			Leaked:       d.leaked,
+			CreatedAt:    d.createdAt,
+		}
+		d.mu.Unlock()
+
+		for i := 0; i+1 < len(g.labels); i += 2 {
+			if lg.Labels == nil {
+				lg.Labels = make(map[string]string)
+			}
+			lg.Labels[g.labels[i]] = g.labels[i+1]
+		}
+
+		frames := runtime.CallersFrames(d.creation)
+		for {
+			f, more := frames.Next()
+			lg.CreationStack = append(lg.CreationStack, Frame{f.Function, f.File, f.Line})
+			if !more {
+				break
+			}
+		}
+		live = append(live, lg)
+	}
+	return live
+}
+
+// DumpLive writes the generators in the registry to w in a format that
+// resembles a goroutine dump:
+//
+//	generator 7 "orders" [suspended, 2m1s]:
+//		pending yield at /src/orders/feed.go:42
+//		created by
+//		main.main()
+//			/src/main.go:17
+func DumpLive(w io.Writer) error {
+	return dumpLive(w, LiveGenerators(), time.Now())
+}
+
+func dumpLive(w io.Writer, live []LiveGenerator, now time.Time) error {
+	var b strings.Builder
+	for i, lg := range live {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "generator %d", lg.ID)
+		if lg.Name != "" {
+			fmt.Fprintf(&b, " %q", lg.Name)
+		}
+		if len(lg.Labels) > 0 {
+			keys := make([]string, 0, len(lg.Labels))
+			for k := range lg.Labels {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			b.WriteString(" {")
+			for i, k := range keys {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				fmt.Fprintf(&b, "%s=%q", k, lg.Labels[k])
+			}
+			b.WriteString("}")
+		}
+		fmt.Fprintf(&b, " [%s, %s", lg.State, now.Sub(lg.Since).Round(time.Millisecond))
+		if lg.Leaked {
+			b.WriteString(", leaked")
+		}
+		b.WriteString("]:\n")
+		if lg.PendingYield != "" {
+			fmt.Fprintf(&b, "\tpending yield at %s\n", lg.PendingYield)
+		}
+		b.WriteString("\tcreated by\n")
+		for _, f := range lg.CreationStack {
+			fmt.Fprintf(&b, "\t%s()\n\t\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,107 @@
+package generator_test
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/generator"
+)
+
+func TestRegistry(t *testing.T) {
+	generator.SetRegistryEnabled(true)
+	defer generator.SetRegistryEnabled(false)
+
+	newSuspended := func() *generator.Generator {
+		return generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				gc.Yield(1)
+				return nil, nil
+			},
+			generator.WithName("numbers"),
+		)
+	}
+
+	t.Run(`states`, func(t *testing.T) {
+		g := newSuspended()
+		if lg := findLive(g.ID()); lg == nil || lg.State != generator.StateCreated || lg.Name != "numbers" {
+			t.Fatalf("got: %+v. wanted: a created generator named numbers", lg)
+		}
+
+		g.Next(nil)
+		lg := findLive(g.ID())
+		if lg == nil || lg.State != generator.StateSuspended {
+			t.Fatalf("got: %+v. wanted: a suspended generator", lg)
+		}
+		if !strings.Contains(lg.PendingYield, "registry_test.go:") {
+			t.Fatalf("got: %v. wanted: a location in registry_test.go", lg.PendingYield)
+		}
+		if fn := lg.CreationStack[0].Function; !strings.HasSuffix(fn, "TestRegistry.func1") {
+			t.Fatalf("got: %v. wanted: the function that called New", fn)
+		}
+
+		g.Next(nil)
+		if lg := findLive(g.ID()); lg != nil {
+			t.Fatalf("got: %+v. wanted: no generator", lg)
+		}
+	})
+	t.Run(`disabled`, func(t *testing.T) {
+		generator.SetRegistryEnabled(false)
+		g := newSuspended()
+		generator.SetRegistryEnabled(true)
+		defer g.Return(nil)
+
+		if lg := findLive(g.ID()); lg != nil {
+			t.Fatalf("got: %+v. wanted: no generator", lg)
+		}
+	})
+	t.Run(`leaked`, func(t *testing.T) {
+		id := func() uint64 {
+			g := newSuspended()
+			g.Next(nil)
+			return g.ID()
+		}()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			lg := findLive(id)
+			if lg == nil {
+				t.Fatal("the leaked generator was removed")
+			}
+			if lg.Leaked {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("the abandoned generator wasn't reported as leaked")
+			}
+			runtime.GC()
+			time.Sleep(time.Millisecond)
+		}
+	})
+	t.Run(`DumpLive`, func(t *testing.T) {
+		g := newSuspended()
+		g.Next(nil)
+		defer g.Return(nil)
+
+		var b bytes.Buffer
+		if err := generator.DumpLive(&b); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, want := range []string{`"numbers" [suspended, `, "\tpending yield at ", "\tcreated by\n"} {
+			if !strings.Contains(b.String(), want) {
+				t.Fatalf("got: %v. wanted it to contain: %q", b.String(), want)
+			}
+		}
+	})
+}
+
+func findLive(id uint64) *generator.LiveGenerator {
+	for _, lg := range generator.LiveGenerators() {
+		if lg.ID == id {
+			return &lg
+		}
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Call is the name of a generator function that was called by the
+// consumer.
+type Call string
+
+const (
+	CallNext   Call = "next"
+	CallReturn Call = "return"
+	CallError  Call = "error"
+)
+
+// Exchange is a call of a generator function and what it returned.
+// Errors are kept as their messages so that the exchange can be encoded
+// as JSON.
+type Exchange struct {
+	Call Call `json:"call"`
+
+	// Value is the argument of `Generator#Next` or `Generator#Return`.
+	Value interface{} `json:"value,omitempty"`
+
+	// Error is the message of the argument of `Generator#Error`.
+	Error string `json:"error,omitempty"`
+
+	Result Result `json:"result"`
+}
+
+// Result is what a generator function returned.
+type Result struct {
+	Value interface{} `json:"value,omitempty"`
+	Done  bool        `json:"done,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// String formats the exchange like `next("a") = (1, false, <nil>)`.
+func (e Exchange) String() string {
+	arg := encodeValue(e.Value)
+	if e.Call == CallError {
+		arg = encodeValue(e.Error)
+	}
+	resultErr := "<nil>"
+	if e.Result.Error != "" {
+		resultErr = encodeValue(e.Result.Error)
+	}
+	return fmt.Sprintf("%s(%s) = (%s, %v, %s)",
+		e.Call, arg, encodeValue(e.Result.Value), e.Result.Done, resultErr)
+}
+
+// Transcript is the conversation between the consumer of a generator
+// and its `Func`, in the order of the calls of the consumer. It can be
+// encoded as JSON, like for golden files. Values decoded from JSON have
+// the types that `encoding/json` decodes into `interface{}`.
+type Transcript struct {
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// Recorder records the calls of the generator functions of the
+// generator that was created using `WithRecorder`. A transcript is the
+// conversation of a single generator, so a recorder can only be used
+// with one generator. It's safe for concurrent use.
+type Recorder struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+
+	// generatorID is the ID of the generator that uses the recorder, or
+	// 0 if it isn't used yet
+	generatorID uint64
+}
+
+// WithRecorder makes the generator record its conversation with the
+// consumer to the recorder. It panics if the recorder has already been
+// used with another generator.
+func WithRecorder(recorder *Recorder) Option {
+	return func(g *Generator) {
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		if recorder.generatorID != 0 && recorder.generatorID != g.id {
+			panic("generator: WithRecorder: the recorder is used by another generator")
+		}
+		recorder.generatorID = g.id
+		g.recorder = recorder
+	}
+}
+
+// Transcript returns the exchanges that were recorded so far.
+func (r *Recorder) Transcript() Transcript {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Transcript{Exchanges: append([]Exchange(nil), r.exchanges...)}
+}
+
+func (r *Recorder) record(
+	call Call,
+	value interface{},
+	err error,
+	resultValue interface{},
+	isDone bool,
+	resultErr error,
+) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exchanges = append(r.exchanges, Exchange{
+		Call:   call,
+		Value:  value,
+		Error:  errorMessage(err),
+		Result: Result{Value: resultValue, Done: isDone, Error: errorMessage(resultErr)},
+	})
+}
+
+// ErrTranscriptEnded is returned by the `Func` of `ReplayProducer`
+// when the consumer makes more calls than the ones in the transcript
+// and the transcript didn't end with the generator being done.
+var ErrTranscriptEnded = errors.New("generator: transcript ended")
+
+// MismatchError is returned when a replayed conversation doesn't go
+// like the one in the transcript.
+type MismatchError struct {
+	// Index is the index of the exchange in the transcript.
+	Index int
+
+	Want Exchange
+	Got  Exchange
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("generator: exchange %d: got %v. wanted: %v", e.Index, e.Got, e.Want)
+}
+
+// ReplayProducer creates a generator that plays the `Func` of the
+// transcript so that a consumer can be tested without the original
+// `Func`. It returns the recorded results as long as the consumer makes
+// the recorded calls, comparing the values by their JSON encoding and
+// the errors by their messages. When a call doesn't match, the `Func`
+// returns a `*MismatchError` with the call's result left empty.
+//
+// Since the `Func` of a generator can't tell how it was started, the
+// argument of the first call isn't checked.
+func ReplayProducer(transcript Transcript) *Generator {
+	exchanges := transcript.Exchanges
+	return New(
+		func(gc *Controller) (interface{}, error) {
+			if len(exchanges) == 0 {
+				return nil, ErrTranscriptEnded
+			}
+			for i := 0; ; i++ {
+				r := exchanges[i].Result
+				if r.Done {
+					return r.Value, errorOf(r.Error)
+				}
+
+				var (
+					value        interface{}
+					shouldReturn bool
+					err          error
+				)
+				if r.Error != "" {
+					value, shouldReturn, err = gc.Error(errors.New(r.Error))
+				} else {
+					value, shouldReturn, err = gc.Yield(r.Value)
+				}
+				if i+1 == len(exchanges) {
+					return nil, ErrTranscriptEnded
+				}
+
+				got := Exchange{Call: CallNext, Value: value}
+				switch {
+				case shouldReturn:
+					got.Call = CallReturn
+				case err != nil:
+					got = Exchange{Call: CallError, Error: err.Error()}
+				}
+				want := exchanges[i+1]
+				if i == 0 && exchanges[0].Call == CallError {
+					// the argument of the call after a first `Error`
+					// is discarded by the controller functions
+					continue
+				}
+				if !sameCall(got, want) {
+					return nil, &MismatchError{Index: i + 1, Want: want, Got: got}
+				}
+			}
+		},
+	)
+}
+
+// ReplayConsumer makes the calls of the transcript to g so that its
+// `Func` can be tested without the original consumer. It returns a
+// `*MismatchError` when g returns something that's not in the
+// transcript, comparing the values by their JSON encoding and the
+// errors by their messages, after stopping g with `Generator#Return`.
+func ReplayConsumer(g *Generator, transcript Transcript) error {
+	for i, want := range transcript.Exchanges {
+		var (
+			value  interface{}
+			isDone bool
+			err    error
+		)
+		switch want.Call {
+		case CallNext:
+			value, isDone, err = g.Next(want.Value)
+		case CallReturn:
+			value, isDone, err = g.Return(want.Value)
+		case CallError:
+			value, isDone, err = g.Error(errors.New(want.Error))
+		default:
+			return fmt.Errorf("generator: exchange %d: unknown call %q", i, want.Call)
+		}
+
+		got := want
+		got.Result = Result{Value: value, Done: isDone, Error: errorMessage(err)}
+		if !sameResult(got.Result, want.Result) {
+			g.Return(nil)
+			return &MismatchError{Index: i, Want: want, Got: got}
+		}
+	}
+	return nil
+}
+
+func sameCall(a, b Exchange) bool {
+	return a.Call == b.Call && a.Error == b.Error &&
+		(a.Call == CallError || sameValue(a.Value, b.Value))
+}
+
+func sameResult(a, b Result) bool {
+	return a.Done == b.Done && a.Error == b.Error && sameValue(a.Value, b.Value)
+}
+
+// sameValue compares the values by their JSON encoding so that values
+// that were decoded from JSON are equal to the original ones.
+func sameValue(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return fmt.Sprint(a) == fmt.Sprint(b)
+	}
+	return bytes.Equal(ja, jb)
+}
+
+func encodeValue(v interface{}) string {
+	if v == nil {
+		return "<nil>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func errorOf(message string) error {
+	if message == "" {
+		return nil
+	}
+	return errors.New(message)
+}
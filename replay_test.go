@@ -0,0 +1,141 @@
+package generator_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// counter yields 1, 2, 3.. until the consumer sends "stop" and delivers
+// the errors it receives back to the consumer.
+func counter(gc *generator.Controller) (interface{}, error) {
+	for i := 1; ; i++ {
+		value, shouldReturn, err := gc.Yield(i)
+		switch {
+		case shouldReturn:
+			return value, nil
+		case err != nil:
+			gc.Error(fmt.Errorf("got %v", err))
+		case value == "stop":
+			return "stopped", nil
+		}
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	rec := &generator.Recorder{}
+	g := generator.New(counter, generator.WithRecorder(rec))
+	g.Next(nil)
+	g.Next("a")
+	g.Error(fmt.Errorf("e1"))
+	g.Next(nil)
+	g.Next("stop")
+	g.Next(nil)
+
+	want := []generator.Exchange{
+		{Call: generator.CallNext, Result: generator.Result{Value: 1}},
+		{Call: generator.CallNext, Value: "a", Result: generator.Result{Value: 2}},
+		{Call: generator.CallError, Error: "e1", Result: generator.Result{Error: "got e1"}},
+		{Call: generator.CallNext, Result: generator.Result{Value: 3}},
+		{Call: generator.CallNext, Value: "stop", Result: generator.Result{Value: "stopped", Done: true}},
+		{Call: generator.CallNext, Result: generator.Result{Done: true}},
+	}
+	if got := rec.Transcript().Exchanges; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v. wanted: %v", got, want)
+	}
+
+	// the conversations of different generators can't be mixed
+	expectPanic(t, "generator: WithRecorder: the recorder is used by another generator", func() {
+		generator.New(counter, generator.WithRecorder(rec))
+	})
+}
+
+func TestReplayConsumer(t *testing.T) {
+	transcript := recordCounter(t)
+
+	t.Run(`same Func`, func(t *testing.T) {
+		if err := generator.ReplayConsumer(generator.New(counter), transcript); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	t.Run(`different Func`, func(t *testing.T) {
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				gc.Yield(1)
+				gc.Yield(20)
+				return nil, nil
+			},
+		)
+		err := generator.ReplayConsumer(g, transcript)
+		var mismatch *generator.MismatchError
+		if !errors.As(err, &mismatch) || mismatch.Index != 1 {
+			t.Fatalf("got: %v. wanted: a mismatch at exchange 1", err)
+		}
+		want := `generator: exchange 1: got next("a") = (20, false, <nil>). wanted: next("a") = (2, false, <nil>)`
+		if err.Error() != want {
+			t.Fatalf("got: %v. wanted: %v", err, want)
+		}
+	})
+}
+
+func TestReplayProducer(t *testing.T) {
+	transcript := recordCounter(t)
+
+	t.Run(`same calls`, func(t *testing.T) {
+		g := generator.ReplayProducer(transcript)
+		if err := generator.ReplayConsumer(g, transcript); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	t.Run(`different calls`, func(t *testing.T) {
+		g := generator.ReplayProducer(transcript)
+		testWith(t).expect(g.Next(nil)).toReturn(float64(1), false, nil)
+		_, isDone, err := g.Next("b")
+		var mismatch *generator.MismatchError
+		if !isDone || !errors.As(err, &mismatch) || mismatch.Index != 1 || mismatch.Got.Value != "b" {
+			t.Fatalf("got: %v, %v. wanted: true, a mismatch at exchange 1", isDone, err)
+		}
+	})
+	t.Run(`more calls`, func(t *testing.T) {
+		g := generator.ReplayProducer(generator.Transcript{Exchanges: transcript.Exchanges[:2]})
+		g.Next(nil)
+		g.Next("a")
+		testWith(t).expect(g.Next(nil)).toReturn(nil, true, generator.ErrTranscriptEnded)
+	})
+	t.Run(`first Return`, func(t *testing.T) {
+		rec := &generator.Recorder{}
+		g := generator.New(counter, generator.WithRecorder(rec))
+		g.Return("r")
+
+		g = generator.ReplayProducer(rec.Transcript())
+		testWith(t).expect(g.Return("r")).toReturn("r", true, nil)
+	})
+}
+
+// recordCounter records a conversation with counter and returns its
+// transcript after encoding and decoding it as JSON.
+func recordCounter(t *testing.T) generator.Transcript {
+	t.Helper()
+
+	rec := &generator.Recorder{}
+	g := generator.New(counter, generator.WithRecorder(rec))
+	g.Next(nil)
+	g.Next("a")
+	g.Error(fmt.Errorf("e1"))
+	g.Next(nil)
+	g.Next("stop")
+
+	b, err := json.Marshal(rec.Transcript())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var transcript generator.Transcript
+	if err := json.Unmarshal(b, &transcript); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return transcript
+}
@@ -0,0 +1,182 @@
+// Package retry restarts generators whose `Func` fails.
+//
+// `New` wraps a producer that can be resumed from the last point it
+// reported, and a `Policy` decides whether and when a failed operation
+// is attempted again. The policy can also be used on its own, like by
+// the paginate package.
+package retry
+
+import (
+	"context"
+	"time"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// Policy decides whether a failed operation should be retried and how
+// long to wait before retrying it. The delay grows exponentially with
+// each retry.
+type Policy struct {
+	// MaxAttempts is the maximum number of times the operation will be
+	// attempted, including the first attempt. Values less than 1 are
+	// treated as 1.
+	MaxAttempts int
+
+	// InitialDelay is the delay before the first retry.
+	InitialDelay time.Duration
+
+	// MaxDelay caps the delay between retries. It's ignored if zero.
+	MaxDelay time.Duration
+
+	// Multiplier is the factor the delay is multiplied by after each
+	// retry. Values less than 1 are treated as 2.
+	Multiplier float64
+
+	// Retryable reports whether the error is transient and the operation
+	// can be retried. If nil, all errors are retryable.
+	Retryable func(err error) bool
+
+	// Clock is used to wait between retries. If nil, the `SystemClock`
+	// is used.
+	Clock generator.Clock
+}
+
+// ShouldRetry reports whether the operation that failed with err on
+// the given attempt, starting from 1, should be attempted again. A nil
+// policy never retries.
+func (p *Policy) ShouldRetry(attempt int, err error) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// delay returns how long to wait after the given failed attempt,
+// starting from 1.
+func (p *Policy) delay(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	d := float64(p.InitialDelay)
+	for i := 1; ; i++ {
+		if p.MaxDelay > 0 && d >= float64(p.MaxDelay) {
+			return p.MaxDelay
+		}
+		if i >= attempt {
+			return time.Duration(d)
+		}
+		d *= multiplier
+	}
+}
+
+// Wait blocks until the delay after the given failed attempt has
+// elapsed or ctx is done. It returns the context's error if ctx is
+// done.
+func (p *Policy) Wait(ctx context.Context, attempt int) error {
+	d := p.delay(attempt)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	clock := p.Clock
+	if clock == nil {
+		clock = generator.SystemClock
+	}
+	select {
+	case <-clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Resumable can be yielded by a producer wrapped by `New` to provide
+// the point from which the sequence can be resumed if the producer
+// fails after yielding it. The consumer only receives the value.
+type Resumable struct {
+	Value  interface{}
+	Cursor interface{}
+}
+
+// Factory creates the producer of a sequence that continues after
+// the cursor of the last `Resumable` that was yielded by the previous
+// producer. The cursor is nil for the first producer or if no
+// `Resumable` has been yielded yet.
+type Factory func(cursor interface{}) *generator.Generator
+
+// New creates a generator that yields the values of the producer
+// created by factory and, when the producer's `Func` returns an error,
+// restarts it from the last cursor according to policy. A nil policy
+// means that the producer is never restarted.
+//
+// The number of attempts counts the consecutive failures of producers
+// that didn't yield any `Resumable` before failing, so a producer that
+// keeps making progress can be restarted any number of times.
+//
+// Errors delivered by the producer using `Controller#Error` are passed
+// to the consumer without restarting the producer. The values and errors
+// passed to `Generator#Next`, `Generator#Return` and `Generator#Error`
+// are passed to the producer in the same way; the producer is not
+// restarted after it has received the consumer's `Generator#Return` or
+// `Generator#Error`. When the producer can't be restarted anymore, the
+// generator returns its error.
+func New(factory Factory, policy *Policy) *generator.Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			var (
+				cursor  interface{}
+				attempt int
+			)
+			for {
+				producer := factory(cursor)
+
+				// stopped is set when the consumer's `Return` or `Error`
+				// has been passed to the producer. it shouldn't be
+				// restarted after that.
+				stopped := false
+
+				value, isDone, err := producer.Next(nil)
+				for !isDone {
+					if r, ok := value.(Resumable); ok && err == nil {
+						cursor, value = r.Cursor, r.Value
+						attempt = 0
+					}
+
+					var (
+						sendValue    interface{}
+						shouldReturn bool
+						thrownErr    error
+					)
+					if err != nil {
+						sendValue, shouldReturn, thrownErr = gc.Error(err)
+					} else {
+						sendValue, shouldReturn, thrownErr = gc.Yield(value)
+					}
+
+					switch {
+					case shouldReturn:
+						stopped = true
+						value, isDone, err = producer.Return(sendValue)
+					case thrownErr != nil:
+						stopped = true
+						value, isDone, err = producer.Error(thrownErr)
+					default:
+						value, isDone, err = producer.Next(sendValue)
+					}
+				}
+				if err == nil || stopped {
+					return value, err
+				}
+
+				attempt++
+				if !policy.ShouldRetry(attempt, err) {
+					return nil, err
+				}
+				if werr := policy.Wait(context.Background(), attempt); werr != nil {
+					return nil, werr
+				}
+			}
+		},
+	)
+}
@@ -0,0 +1,177 @@
+package retry_test
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/retry"
+)
+
+// flakySource creates producers of the numbers from 0 until n that
+// resume after the cursor and fail after yielding every failEvery
+// numbers, or right away if failEvery is 0.
+func flakySource(n, failEvery int, starts *[]interface{}) retry.Factory {
+	return func(cursor interface{}) *generator.Generator {
+		*starts = append(*starts, cursor)
+		return generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				from := 0
+				if cursor != nil {
+					from = cursor.(int) + 1
+				}
+				for i, yielded := from, 0; i < n; i, yielded = i+1, yielded+1 {
+					if yielded == failEvery {
+						return nil, fmt.Errorf("failed at %d", i)
+					}
+					_, shouldReturn, _ := gc.Yield(retry.Resumable{Value: i * 10, Cursor: i})
+					if shouldReturn {
+						return "stopped", nil
+					}
+				}
+				return "done", nil
+			},
+		)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Run(`resumes from cursor`, func(t *testing.T) {
+		var starts []interface{}
+		clock := &fakeClock{}
+		policy := &retry.Policy{MaxAttempts: 2, InitialDelay: time.Second, Clock: clock}
+		g := retry.New(flakySource(5, 2, &starts), policy)
+
+		for _, want := range []interface{}{0, 10, 20, 30, 40} {
+			testWith(t).expect(g.Next(nil)).toReturn(want, false, nil)
+		}
+		testWith(t).expect(g.Next(nil)).toReturn("done", true, nil)
+		if want := []interface{}{nil, 1, 3}; !reflect.DeepEqual(starts, want) {
+			t.Fatalf("got: %v. wanted: %v", starts, want)
+		}
+		// progress was made before each failure so the delay doesn't grow
+		if want := []time.Duration{time.Second, time.Second}; !reflect.DeepEqual(clock.waits(), want) {
+			t.Fatalf("got: %v. wanted: %v", clock.waits(), want)
+		}
+	})
+	t.Run(`gives up without progress`, func(t *testing.T) {
+		var starts []interface{}
+		clock := &fakeClock{}
+		policy := &retry.Policy{MaxAttempts: 3, InitialDelay: time.Second, Clock: clock}
+		g := retry.New(flakySource(5, 0, &starts), policy)
+
+		v, isDone, err := g.Next(nil)
+		if v != nil || !isDone || err == nil || err.Error() != "failed at 0" {
+			t.Fatalf("got: %v, %v, %v. wanted: <nil>, true, failed at 0", v, isDone, err)
+		}
+		if len(starts) != 3 {
+			t.Fatalf("got: %v. wanted: 3 starts", starts)
+		}
+		if want := []time.Duration{time.Second, 2 * time.Second}; !reflect.DeepEqual(clock.waits(), want) {
+			t.Fatalf("got: %v. wanted: %v", clock.waits(), want)
+		}
+	})
+	t.Run(`non-retryable error`, func(t *testing.T) {
+		var starts []interface{}
+		policy := &retry.Policy{
+			MaxAttempts: 3,
+			Retryable:   func(error) bool { return false },
+		}
+		g := retry.New(flakySource(5, 1, &starts), policy)
+		testWith(t).expect(g.Next(nil)).toReturn(0, false, nil)
+		if _, isDone, err := g.Next(nil); !isDone || err == nil {
+			t.Fatalf("got: %v, %v. wanted: true, an error", isDone, err)
+		}
+		if len(starts) != 1 {
+			t.Fatalf("got: %v. wanted: 1 start", starts)
+		}
+	})
+	t.Run(`Return("r")`, func(t *testing.T) {
+		var starts []interface{}
+		g := retry.New(flakySource(5, 2, &starts), nil)
+		testWith(t).expect(g.Next(nil)).toReturn(0, false, nil)
+		testWith(t).expect(g.Return("r")).toReturn("stopped", true, nil)
+	})
+	t.Run(`Controller.Error and Error(<e1>)`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		e2 := fmt.Errorf("e2")
+		g := retry.New(func(interface{}) *generator.Generator {
+			return generator.New(
+				func(gc *generator.Controller) (interface{}, error) {
+					v, _, _ := gc.Error(e1)
+					_, _, err := gc.Yield(v)
+					return nil, err
+				},
+			)
+		}, &retry.Policy{MaxAttempts: 5})
+		testWith(t).expect(g.Next(nil)).toReturn(nil, false, e1)
+		testWith(t).expect(g.Next("a")).toReturn("a", false, nil)
+		testWith(t).expect(g.Error(e2)).toReturn(nil, true, e2)
+	})
+}
+
+// fakeClock is a `generator.Clock` whose timers fire immediately. It
+// records the durations that were waited for and advances its time by
+// them.
+type fakeClock struct {
+	mu        sync.Mutex
+	now       time.Time
+	durations []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.durations = append(c.durations, d)
+	c.now = c.now.Add(d)
+
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func (c *fakeClock) waits() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration{}, c.durations...)
+}
+
+// utility stuff =====================================================
+
+type tw struct {
+	t *testing.T
+}
+
+type twe struct {
+	tw *tw
+	v  interface{}
+	r  bool
+	e  error
+}
+
+func testWith(t *testing.T) *tw {
+	return &tw{t}
+}
+
+func (tw *tw) expect(v interface{}, r bool, e error) *twe {
+	return &twe{tw, v, r, e}
+}
+
+func (twe *twe) toReturn(v interface{}, r bool, e error) {
+	if v != twe.v || r != twe.r || e != twe.e {
+		twe.tw.t.Helper()
+		twe.tw.t.Fatalf(
+			"got: %v, %v, %v. wanted: %v, %v, %v",
+			twe.v, twe.r, twe.e, v, r, e,
+		)
+	}
+}
@@ -0,0 +1,283 @@
+// Package sched runs many generators as cooperative tasks.
+//
+// A task is a generator `Func` that gives up its turn by calling
+// `Controller#Yield`. The value it yields is a request to the scheduler:
+// `Sleep` or `SleepUntil` to be resumed at a later tick, `Wait` to be
+// resumed when an event is signalled, `Signal` to signal an event, or
+// any other value to simply be resumed at the next tick.
+//
+// The scheduler doesn't use real time or spawn goroutines of its own, so
+// the order in which the tasks run only depends on the order in which
+// they were spawned, their priorities and their requests.
+package sched
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// ErrDeadlock is returned by `Scheduler#Run` when all of the remaining
+// tasks are waiting for events that can no longer be signalled.
+var ErrDeadlock = errors.New("sched: all tasks are waiting for events")
+
+// Policy decides the order in which the runnable tasks take their turns
+// within a tick.
+type Policy int
+
+const (
+	// RoundRobin runs the tasks in the order they were spawned.
+	RoundRobin Policy = iota
+
+	// ByPriority runs the tasks with higher priorities first. Tasks with
+	// the same priority run in the order they were spawned.
+	ByPriority
+)
+
+// Sleep is yielded by a task to be resumed after the number of ticks.
+// The task receives the tick at which it was resumed.
+type Sleep struct {
+	Ticks int64
+}
+
+// SleepUntil is yielded by a task to be resumed at the tick. The task
+// receives the tick at which it was resumed.
+type SleepUntil struct {
+	Tick int64
+}
+
+// Wait is yielded by a task to be resumed when the event is signalled.
+// The task receives the value the event was signalled with.
+type Wait struct {
+	Event string
+}
+
+// Signal is yielded by a task to signal the event with the value. The
+// task is resumed at the next tick like the tasks waiting for the event.
+type Signal struct {
+	Event string
+	Value interface{}
+}
+
+type taskState int
+
+const (
+	runnable taskState = iota
+	sleeping
+	waiting
+	done
+)
+
+// Task is a generator run by a `Scheduler`.
+type Task struct {
+	// ID is the order in which the task was spawned, starting from 0.
+	ID       int
+	Name     string
+	Priority int
+
+	g      *generator.Generator
+	state  taskState
+	wakeAt int64
+	event  string
+
+	// resumeValue is the value that the task will receive when it
+	// takes its next turn
+	resumeValue interface{}
+
+	result interface{}
+	err    error
+}
+
+// Done reports whether the task has completed.
+func (t *Task) Done() bool {
+	return t.state == done
+}
+
+// Result returns the value and the error returned by the task's `Func`.
+// If the task delivered an error using `Controller#Error`, the task was
+// stopped and the error is returned instead.
+func (t *Task) Result() (interface{}, error) {
+	return t.result, t.err
+}
+
+// Scheduler drives tasks tick by tick. It's not safe for concurrent use.
+type Scheduler struct {
+	policy Policy
+	tick   int64
+	tasks  []*Task
+}
+
+// New creates a scheduler that orders the tasks using the policy.
+func New(policy Policy) *Scheduler {
+	return &Scheduler{policy: policy}
+}
+
+// Spawn adds a task that will take its first turn at the next step.
+func (s *Scheduler) Spawn(name string, priority int, fn generator.Func) *Task {
+	t := &Task{
+		ID:       len(s.tasks),
+		Name:     name,
+		Priority: priority,
+		g:        generator.New(fn),
+	}
+	s.tasks = append(s.tasks, t)
+	return t
+}
+
+// Tick returns the current tick.
+func (s *Scheduler) Tick() int64 {
+	return s.tick
+}
+
+// Tasks returns the tasks in the order they were spawned.
+func (s *Scheduler) Tasks() []*Task {
+	return append([]*Task{}, s.tasks...)
+}
+
+// Done reports whether all of the tasks have completed.
+func (s *Scheduler) Done() bool {
+	for _, t := range s.tasks {
+		if t.state != done {
+			return false
+		}
+	}
+	return true
+}
+
+// Signal signals the event with the value, making the tasks waiting for
+// it runnable.
+func (s *Scheduler) Signal(event string, value interface{}) {
+	for _, t := range s.tasks {
+		if t.state == waiting && t.event == event {
+			t.state = runnable
+			t.resumeValue = value
+		}
+	}
+}
+
+// Step gives each of the tasks that are runnable at the current tick
+// one turn and then advances to the next tick. Tasks made runnable
+// during the step take their turn at the next step. It returns the
+// number of tasks that took a turn.
+func (s *Scheduler) Step() int {
+	for _, t := range s.tasks {
+		if t.state == sleeping && t.wakeAt <= s.tick {
+			t.state = runnable
+			t.resumeValue = s.tick
+		}
+	}
+
+	var turn []*Task
+	for _, t := range s.tasks {
+		if t.state == runnable {
+			turn = append(turn, t)
+		}
+	}
+	if s.policy == ByPriority {
+		sort.SliceStable(turn, func(i, j int) bool {
+			return turn[i].Priority > turn[j].Priority
+		})
+	}
+
+	for _, t := range turn {
+		s.resume(t)
+	}
+	s.tick++
+	return len(turn)
+}
+
+// Run steps until all of the tasks have completed. When no task is
+// runnable, it skips to the earliest tick at which a sleeping task wakes
+// up. If the remaining tasks are all waiting for events, they are
+// stopped with `Generator#Return` and marked as done, so their
+// goroutines don't leak, and `ErrDeadlock` is returned.
+//
+// The errors of the tasks that failed are joined and returned.
+func (s *Scheduler) Run() error {
+	for !s.Done() {
+		if s.Step() > 0 {
+			continue
+		}
+
+		wakeAt, ok := s.nextWake()
+		if !ok {
+			s.stopAll()
+			return errors.Join(append(s.errs(), ErrDeadlock)...)
+		}
+		if wakeAt > s.tick {
+			s.tick = wakeAt
+		}
+	}
+	return errors.Join(s.errs()...)
+}
+
+// nextWake returns the earliest tick at which a sleeping task wakes up.
+func (s *Scheduler) nextWake() (int64, bool) {
+	var (
+		wakeAt int64
+		found  bool
+	)
+	for _, t := range s.tasks {
+		if t.state == sleeping && (!found || t.wakeAt < wakeAt) {
+			wakeAt, found = t.wakeAt, true
+		}
+	}
+	return wakeAt, found
+}
+
+// stopAll stops the tasks that haven't completed.
+func (s *Scheduler) stopAll() {
+	for _, t := range s.tasks {
+		if t.state != done {
+			t.result, _, t.err = t.g.Return(nil)
+			t.state = done
+		}
+	}
+}
+
+func (s *Scheduler) errs() []error {
+	var errs []error
+	for _, t := range s.tasks {
+		if t.err != nil {
+			errs = append(errs, fmt.Errorf("sched: task %d (%s): %w", t.ID, t.Name, t.err))
+		}
+	}
+	return errs
+}
+
+// resume gives the task a turn and handles its request.
+func (s *Scheduler) resume(t *Task) {
+	value, isDone, err := t.g.Next(t.resumeValue)
+	t.resumeValue = nil
+
+	switch {
+	case isDone:
+		t.state, t.result, t.err = done, value, err
+		return
+	case err != nil:
+		t.g.Return(nil)
+		t.state, t.err = done, err
+		return
+	}
+
+	switch req := value.(type) {
+	case Sleep:
+		s.sleepUntil(t, s.tick+req.Ticks)
+	case SleepUntil:
+		s.sleepUntil(t, req.Tick)
+	case Wait:
+		t.state, t.event = waiting, req.Event
+	case Signal:
+		s.Signal(req.Event, req.Value)
+	}
+}
+
+func (s *Scheduler) sleepUntil(t *Task, tick int64) {
+	// the task has already taken its turn at the current tick
+	if tick <= s.tick {
+		tick = s.tick + 1
+	}
+	t.state, t.wakeAt = sleeping, tick
+}
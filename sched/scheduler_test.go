@@ -0,0 +1,159 @@
+package sched_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/sched"
+)
+
+func TestScheduler_RoundRobin(t *testing.T) {
+	var trace []string
+	s := sched.New(sched.RoundRobin)
+	for _, name := range []string{"a", "b"} {
+		name := name
+		s.Spawn(name, 0, func(gc *generator.Controller) (interface{}, error) {
+			for i := 0; i < 2; i++ {
+				trace = append(trace, fmt.Sprint(name, i))
+				gc.Yield(nil)
+			}
+			return name, nil
+		})
+	}
+
+	if err := s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a0", "b0", "a1", "b1"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("got: %v. wanted: %v", trace, want)
+	}
+	for _, task := range s.Tasks() {
+		if v, err := task.Result(); !task.Done() || v != task.Name || err != nil {
+			t.Fatalf("got: %v, %v, %v. wanted: true, %v, <nil>", task.Done(), v, err, task.Name)
+		}
+	}
+}
+
+func TestScheduler_ByPriority(t *testing.T) {
+	var trace []string
+	s := sched.New(sched.ByPriority)
+	for i, name := range []string{"low", "high", "mid", "high2"} {
+		name, priority := name, []int{0, 2, 1, 2}[i]
+		s.Spawn(name, priority, func(gc *generator.Controller) (interface{}, error) {
+			trace = append(trace, name)
+			return nil, nil
+		})
+	}
+
+	if err := s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"high", "high2", "mid", "low"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("got: %v. wanted: %v", trace, want)
+	}
+}
+
+func TestScheduler_Sleep(t *testing.T) {
+	var trace []string
+	s := sched.New(sched.RoundRobin)
+	s.Spawn("sleeper", 0, func(gc *generator.Controller) (interface{}, error) {
+		tick, _, _ := gc.Yield(sched.Sleep{Ticks: 10})
+		trace = append(trace, fmt.Sprint("woke at ", tick))
+		tick, _, _ = gc.Yield(sched.SleepUntil{Tick: 12})
+		trace = append(trace, fmt.Sprint("woke at ", tick))
+		return nil, nil
+	})
+
+	if err := s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"woke at 10", "woke at 12"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("got: %v. wanted: %v", trace, want)
+	}
+	if s.Tick() != 13 {
+		t.Fatalf("got: %v. wanted: 13", s.Tick())
+	}
+}
+
+func TestScheduler_WaitAndSignal(t *testing.T) {
+	var trace []string
+	s := sched.New(sched.RoundRobin)
+	s.Spawn("consumer", 0, func(gc *generator.Controller) (interface{}, error) {
+		v, _, _ := gc.Yield(sched.Wait{Event: "ready"})
+		trace = append(trace, fmt.Sprint("received ", v))
+		return nil, nil
+	})
+	s.Spawn("producer", 0, func(gc *generator.Controller) (interface{}, error) {
+		gc.Yield(sched.Sleep{Ticks: 3})
+		trace = append(trace, "signalling")
+		gc.Yield(sched.Signal{Event: "ready", Value: 42})
+		trace = append(trace, "signalled")
+		return nil, nil
+	})
+
+	if err := s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"signalling", "received 42", "signalled"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("got: %v. wanted: %v", trace, want)
+	}
+}
+
+func TestScheduler_Deadlock(t *testing.T) {
+	stopped := false
+
+	s := sched.New(sched.RoundRobin)
+	s.Spawn("waiter", 0, func(gc *generator.Controller) (interface{}, error) {
+		_, stopped, _ = gc.Yield(sched.Wait{Event: "never"})
+		return "stopped", nil
+	})
+
+	if err := s.Run(); !errors.Is(err, sched.ErrDeadlock) {
+		t.Fatalf("got: %v. wanted: %v", err, sched.ErrDeadlock)
+	}
+	if !stopped || !s.Done() {
+		t.Fatalf("got: %v, %v. wanted: true, true", stopped, s.Done())
+	}
+	if v, err := s.Tasks()[0].Result(); v != "stopped" || err != nil {
+		t.Fatalf("got: %v, %v. wanted: stopped, <nil>", v, err)
+	}
+
+	// the stopped task doesn't take any more turns
+	s.Signal("never", nil)
+	if err := s.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestScheduler_Errors(t *testing.T) {
+	e1 := fmt.Errorf("e1")
+	e2 := fmt.Errorf("e2")
+	stopped := false
+
+	s := sched.New(sched.RoundRobin)
+	s.Spawn("returns", 0, func(gc *generator.Controller) (interface{}, error) {
+		return nil, e1
+	})
+	s.Spawn("delivers", 0, func(gc *generator.Controller) (interface{}, error) {
+		_, stopped, _ = gc.Error(e2)
+		return nil, nil
+	})
+	s.Spawn("ok", 0, func(gc *generator.Controller) (interface{}, error) {
+		return nil, nil
+	})
+
+	err := s.Run()
+	if !errors.Is(err, e1) || !errors.Is(err, e2) || !stopped {
+		t.Fatalf("got: %v, %v. wanted: e1 and e2, true", err, stopped)
+	}
+	if _, err := s.Tasks()[1].Result(); err != e2 {
+		t.Fatalf("got: %v. wanted: e2", err)
+	}
+}
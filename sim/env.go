@@ -0,0 +1,348 @@
+// Package sim provides discrete-event simulation in the style of SimPy.
+//
+// Processes are generator `Func`s that yield events, like timeouts or
+// resource requests, to wait for them. An `Environment` keeps a virtual
+// clock and resumes the processes in time order when the events they
+// are waiting for are processed, so no real time passes while a
+// simulation runs:
+//
+//	env := sim.NewEnvironment()
+//	defer env.Close()
+//	env.Process(func(gc *generator.Controller) (interface{}, error) {
+//		for {
+//			fmt.Println("tick at", env.Now())
+//			if v, shouldReturn, err := gc.Yield(env.Timeout(1, nil)); shouldReturn {
+//				return v, nil
+//			} else if err != nil {
+//				return nil, err
+//			}
+//		}
+//	})
+//	env.RunUntil(3)
+//
+// Processes that are still waiting for events when the simulation ends
+// keep their goroutines until the environment is closed.
+package sim
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// Environment runs a simulation. It's not safe for concurrent use; all
+// of the processes of an environment take turns in the goroutine that
+// calls `Run` or `Step`.
+type Environment struct {
+	now   float64
+	seq   int
+	queue eventQueue
+
+	// errs are the errors of the failed processes that nothing was
+	// waiting for
+	errs []error
+
+	// processes are the processes that were started, including some
+	// that have finished, which are only removed when the slice grows
+	processes []*Process
+}
+
+// NewEnvironment creates an environment whose clock starts at 0.
+func NewEnvironment() *Environment {
+	return &Environment{}
+}
+
+// Now returns the current simulation time.
+func (env *Environment) Now() float64 {
+	return env.now
+}
+
+// Event creates an untriggered event. It's triggered using
+// `Event#Succeed` or `Event#Fail`.
+func (env *Environment) Event() *Event {
+	return &Event{env: env}
+}
+
+// Timeout creates an event that succeeds with the value after the delay.
+func (env *Environment) Timeout(delay float64, value interface{}) *Event {
+	if delay < 0 {
+		delay = 0
+	}
+	e := env.Event()
+	e.triggered, e.value = true, value
+	env.schedule(e, delay)
+	return e
+}
+
+// Step processes the next scheduled event, advancing the clock to the
+// time the event was scheduled at. It returns false if there are no
+// more events.
+func (env *Environment) Step() bool {
+	if env.queue.Len() == 0 {
+		return false
+	}
+	item := heap.Pop(&env.queue).(*queueItem)
+	env.now = item.at
+
+	e := item.event
+	e.processed = true
+	callbacks := e.callbacks
+	e.callbacks = nil
+	for _, cb := range callbacks {
+		cb(e)
+	}
+	if e.process != nil && e.err != nil && len(callbacks) == 0 {
+		env.errs = append(env.errs, e.err)
+	}
+	return true
+}
+
+// Run processes events until there are no more scheduled events. It
+// returns the errors of the processes that failed without any other
+// process waiting for them, joined together.
+func (env *Environment) Run() error {
+	for env.Step() {
+	}
+	return env.takeErrs()
+}
+
+// RunUntil processes the events that are scheduled before or at the
+// time and then advances the clock to it. It returns errors like `Run`.
+func (env *Environment) RunUntil(until float64) error {
+	for env.queue.Len() > 0 && env.queue[0].at <= until {
+		env.Step()
+	}
+	if until > env.now {
+		env.now = until
+	}
+	return env.takeErrs()
+}
+
+// Close stops the processes that haven't finished with
+// `Generator#Return`, so their `Controller#Yield` returns true as the
+// shouldReturn result, and discards the scheduled events. The stopped
+// processes are triggered with the values they return. The environment
+// shouldn't be used after it's closed.
+func (env *Environment) Close() {
+	for _, p := range env.processes {
+		if !p.triggered {
+			value, _, err := p.g.Return(nil)
+			p.finish(value, err)
+		}
+	}
+	env.processes = nil
+	env.queue = nil
+}
+
+func (env *Environment) takeErrs() error {
+	err := errors.Join(env.errs...)
+	env.errs = nil
+	return err
+}
+
+func (env *Environment) schedule(e *Event, delay float64) {
+	env.seq++
+	heap.Push(&env.queue, &queueItem{at: env.now + delay, seq: env.seq, event: e})
+}
+
+// Event is something that happens at a point in simulation time. A
+// process waits for an event by yielding it and is resumed with the
+// event's value, or receives the event's error from `Controller#Yield`
+// if the event failed.
+type Event struct {
+	env *Environment
+
+	// triggered is set when the event has been scheduled to be
+	// processed. processed is set when it has been processed, which is
+	// when its callbacks are called.
+	triggered bool
+	processed bool
+
+	value interface{}
+	err   error
+
+	callbacks []func(*Event)
+
+	// process is set if this is the event of a process
+	process *Process
+}
+
+// Succeed triggers the event with the value. It has no effect if the
+// event has already been triggered.
+func (e *Event) Succeed(value interface{}) *Event {
+	if !e.triggered {
+		e.triggered, e.value = true, value
+		e.env.schedule(e, 0)
+	}
+	return e
+}
+
+// Fail triggers the event with the error. It has no effect if the event
+// has already been triggered.
+func (e *Event) Fail(err error) *Event {
+	if !e.triggered {
+		e.triggered, e.err = true, err
+		e.env.schedule(e, 0)
+	}
+	return e
+}
+
+// Triggered reports whether the event has been triggered.
+func (e *Event) Triggered() bool {
+	return e.triggered
+}
+
+// Processed reports whether the event has been processed.
+func (e *Event) Processed() bool {
+	return e.processed
+}
+
+// Value returns the value and the error the event was triggered with.
+func (e *Event) Value() (interface{}, error) {
+	return e.value, e.err
+}
+
+// onProcessed calls cb when the event is processed. If the event has
+// already been processed, cb is called when an event scheduled at the
+// current time is processed instead.
+func (e *Event) onProcessed(cb func(*Event)) {
+	if e.processed {
+		e.env.Timeout(0, nil).onProcessed(func(*Event) {
+			cb(e)
+		})
+		return
+	}
+	e.callbacks = append(e.callbacks, cb)
+}
+
+// Process is a generator `Func` run by an `Environment`. It's also an
+// event that is triggered when the `Func` returns, so other processes
+// can wait for it by yielding it.
+type Process struct {
+	*Event
+
+	g *generator.Generator
+}
+
+// Process starts a process with fn at the current simulation time.
+//
+// fn waits for an event by yielding a `*Event` or a `*Process`. If it
+// yields anything else or delivers an error using `Controller#Error`,
+// the process is stopped and fails with an error.
+func (env *Environment) Process(fn generator.Func) *Process {
+	p := &Process{g: generator.New(fn)}
+	p.Event = &Event{env: env, process: p}
+	env.addProcess(p)
+
+	// start the process when the initialization event is processed so
+	// that processes started at the same time run in order
+	env.Timeout(0, nil).onProcessed(func(*Event) {
+		p.resume(nil, nil)
+	})
+	return p
+}
+
+// addProcess keeps the process so that it can be stopped by `Close`.
+// The finished processes are dropped before the slice has to grow.
+func (env *Environment) addProcess(p *Process) {
+	if len(env.processes) == cap(env.processes) {
+		running := env.processes[:0]
+		for _, q := range env.processes {
+			if !q.triggered {
+				running = append(running, q)
+			}
+		}
+		for i := len(running); i < len(env.processes); i++ {
+			env.processes[i] = nil
+		}
+		env.processes = running
+	}
+	env.processes = append(env.processes, p)
+}
+
+// finish triggers the event of the process with the result of its
+// `Func`.
+func (p *Process) finish(value interface{}, err error) {
+	if err != nil {
+		p.Event.Fail(err)
+	} else {
+		p.Event.Succeed(value)
+	}
+}
+
+// resume passes the value or the error to the process and waits for the
+// event yielded by it.
+func (p *Process) resume(value interface{}, err error) {
+	var (
+		yielded interface{}
+		isDone  bool
+	)
+	if err != nil {
+		yielded, isDone, err = p.g.Error(err)
+	} else {
+		yielded, isDone, err = p.g.Next(value)
+	}
+
+	if isDone {
+		p.finish(yielded, err)
+		return
+	}
+	if err != nil {
+		p.g.Return(nil)
+		p.Event.Fail(err)
+		return
+	}
+
+	var target *Event
+	switch y := yielded.(type) {
+	case *Event:
+		target = y
+	case *Process:
+		target = y.Event
+	default:
+		p.g.Return(nil)
+		p.Event.Fail(fmt.Errorf("sim: process yielded %T, not an event", yielded))
+		return
+	}
+	target.onProcessed(func(e *Event) {
+		p.resume(e.value, e.err)
+	})
+}
+
+type queueItem struct {
+	at    float64
+	seq   int
+	event *Event
+}
+
+// eventQueue orders the scheduled events by time and then by the order
+// they were scheduled.
+type eventQueue []*queueItem
+
+func (q eventQueue) Len() int {
+	return len(q)
+}
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *eventQueue) Push(x interface{}) {
+	*q = append(*q, x.(*queueItem))
+}
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,186 @@
+package sim
+
+// Resource is a resource that can be used by a limited number of
+// processes at a time, like a semaphore. Requests are granted in the
+// order they were made.
+type Resource struct {
+	env      *Environment
+	capacity int
+	users    int
+	queue    []*Event
+}
+
+// NewResource creates a resource that can be used by capacity processes
+// at a time.
+func NewResource(env *Environment, capacity int) *Resource {
+	return &Resource{env: env, capacity: capacity}
+}
+
+// Request returns an event that succeeds when the resource is granted.
+// The process that was granted the resource should call `Release` when
+// it's done using it.
+func (r *Resource) Request() *Event {
+	e := r.env.Event()
+	if r.users < r.capacity {
+		r.users++
+		e.Succeed(nil)
+	} else {
+		r.queue = append(r.queue, e)
+	}
+	return e
+}
+
+// Release releases the resource, granting it to the next request in the
+// queue, if any.
+func (r *Resource) Release() {
+	if len(r.queue) > 0 {
+		next := r.queue[0]
+		r.queue = r.queue[1:]
+		next.Succeed(nil)
+		return
+	}
+	if r.users > 0 {
+		r.users--
+	}
+}
+
+// Users returns the number of processes that are using the resource.
+func (r *Resource) Users() int {
+	return r.users
+}
+
+// Queued returns the number of requests waiting for the resource.
+func (r *Resource) Queued() int {
+	return len(r.queue)
+}
+
+type storePut struct {
+	event *Event
+	item  interface{}
+}
+
+// Store is a FIFO queue of items with an optional capacity.
+type Store struct {
+	env      *Environment
+	capacity int
+	items    []interface{}
+	puts     []storePut
+	gets     []*Event
+}
+
+// NewStore creates a store that can hold capacity items. A capacity of 0
+// or less means that the store is unbounded.
+func NewStore(env *Environment, capacity int) *Store {
+	return &Store{env: env, capacity: capacity}
+}
+
+// Put returns an event that succeeds when the item has been added to
+// the store, which may have to wait until there is space for it.
+func (s *Store) Put(item interface{}) *Event {
+	e := s.env.Event()
+	s.puts = append(s.puts, storePut{event: e, item: item})
+	s.settle()
+	return e
+}
+
+// Get returns an event that succeeds with the oldest item in the store,
+// which may have to wait until an item is put into it.
+func (s *Store) Get() *Event {
+	e := s.env.Event()
+	s.gets = append(s.gets, e)
+	s.settle()
+	return e
+}
+
+// Items returns the number of items in the store.
+func (s *Store) Items() int {
+	return len(s.items)
+}
+
+// settle grants the pending puts and gets, in the order they were made,
+// for as long as possible.
+func (s *Store) settle() {
+	for {
+		switch {
+		case len(s.puts) > 0 && (s.capacity <= 0 || len(s.items) < s.capacity):
+			put := s.puts[0]
+			s.puts = s.puts[1:]
+			s.items = append(s.items, put.item)
+			put.event.Succeed(nil)
+		case len(s.gets) > 0 && len(s.items) > 0:
+			get := s.gets[0]
+			s.gets = s.gets[1:]
+			item := s.items[0]
+			s.items = s.items[1:]
+			get.Succeed(item)
+		default:
+			return
+		}
+	}
+}
+
+type containerRequest struct {
+	event  *Event
+	amount float64
+}
+
+// Container holds a continuous amount of something, like the fuel in a
+// tank, up to a capacity.
+type Container struct {
+	env      *Environment
+	capacity float64
+	level    float64
+	puts     []containerRequest
+	gets     []containerRequest
+}
+
+// NewContainer creates a container with the capacity and the initial
+// level.
+func NewContainer(env *Environment, capacity, level float64) *Container {
+	return &Container{env: env, capacity: capacity, level: level}
+}
+
+// Put returns an event that succeeds when the amount has been added to
+// the container, which may have to wait until there is space for it.
+func (c *Container) Put(amount float64) *Event {
+	e := c.env.Event()
+	c.puts = append(c.puts, containerRequest{event: e, amount: amount})
+	c.settle()
+	return e
+}
+
+// Get returns an event that succeeds when the amount has been taken
+// from the container, which may have to wait until there is enough of
+// it.
+func (c *Container) Get(amount float64) *Event {
+	e := c.env.Event()
+	c.gets = append(c.gets, containerRequest{event: e, amount: amount})
+	c.settle()
+	return e
+}
+
+// Level returns the amount in the container.
+func (c *Container) Level() float64 {
+	return c.level
+}
+
+// settle grants the pending puts and gets, in the order they were made,
+// for as long as possible.
+func (c *Container) settle() {
+	for {
+		switch {
+		case len(c.puts) > 0 && c.level+c.puts[0].amount <= c.capacity:
+			put := c.puts[0]
+			c.puts = c.puts[1:]
+			c.level += put.amount
+			put.event.Succeed(nil)
+		case len(c.gets) > 0 && c.gets[0].amount <= c.level:
+			get := c.gets[0]
+			c.gets = c.gets[1:]
+			c.level -= get.amount
+			get.event.Succeed(nil)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package sim_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/sim"
+)
+
+func TestEnvironment_Timeout(t *testing.T) {
+	var trace []string
+	env := sim.NewEnvironment()
+	env.Process(func(gc *generator.Controller) (interface{}, error) {
+		for {
+			trace = append(trace, fmt.Sprint("a@", env.Now()))
+			gc.Yield(env.Timeout(2, nil))
+		}
+	})
+	env.Process(func(gc *generator.Controller) (interface{}, error) {
+		for {
+			trace = append(trace, fmt.Sprint("b@", env.Now()))
+			v, _, _ := gc.Yield(env.Timeout(3, "x"))
+			trace = append(trace, fmt.Sprint("b got ", v))
+		}
+	})
+
+	if err := env.RunUntil(6); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"a@0", "b@0", "a@2", "b got x", "b@3",
+		"a@4", "b got x", "b@6", "a@6",
+	}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("got: %v. wanted: %v", trace, want)
+	}
+	if env.Now() != 6 {
+		t.Fatalf("got: %v. wanted: 6", env.Now())
+	}
+}
+
+func TestEnvironment_WaitForProcess(t *testing.T) {
+	e1 := fmt.Errorf("e1")
+	env := sim.NewEnvironment()
+	child := env.Process(func(gc *generator.Controller) (interface{}, error) {
+		gc.Yield(env.Timeout(5, nil))
+		return "child done", nil
+	})
+	failing := env.Process(func(gc *generator.Controller) (interface{}, error) {
+		gc.Yield(env.Timeout(1, nil))
+		return nil, e1
+	})
+
+	var got []interface{}
+	env.Process(func(gc *generator.Controller) (interface{}, error) {
+		_, _, err := gc.Yield(failing)
+		got = append(got, err, env.Now())
+		v, _, _ := gc.Yield(child)
+		got = append(got, v, env.Now())
+		return nil, nil
+	})
+
+	if err := env.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{e1, 1.0, "child done", 5.0}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v. wanted: %v", got, want)
+	}
+}
+
+func TestEnvironment_UnhandledFailure(t *testing.T) {
+	e1 := fmt.Errorf("e1")
+	env := sim.NewEnvironment()
+	env.Process(func(gc *generator.Controller) (interface{}, error) {
+		return nil, e1
+	})
+	p := env.Process(func(gc *generator.Controller) (interface{}, error) {
+		gc.Yield("not an event")
+		return nil, nil
+	})
+
+	err := env.Run()
+	if !errors.Is(err, e1) {
+		t.Fatalf("got: %v. wanted: e1", err)
+	}
+	if _, perr := p.Value(); perr == nil || !p.Processed() {
+		t.Fatalf("got: %v, %v. wanted: an error, true", perr, p.Processed())
+	}
+}
+
+func TestEnvironment_Close(t *testing.T) {
+	var stopped []string
+	env := sim.NewEnvironment()
+	ticker := func(name string) generator.Func {
+		return func(gc *generator.Controller) (interface{}, error) {
+			for {
+				if _, shouldReturn, _ := gc.Yield(env.Timeout(1, nil)); shouldReturn {
+					stopped = append(stopped, name)
+					return name, nil
+				}
+			}
+		}
+	}
+	running := env.Process(ticker("running"))
+	done := env.Process(func(gc *generator.Controller) (interface{}, error) {
+		return "done", nil
+	})
+	if err := env.RunUntil(3); err != nil {
+		t.Fatal(err)
+	}
+	notStarted := env.Process(ticker("not started"))
+
+	env.Close()
+	if want := []string{"running", "not started"}; !reflect.DeepEqual(stopped, want) {
+		t.Fatalf("got: %v. wanted: %v", stopped, want)
+	}
+	for _, p := range []*sim.Process{running, done, notStarted} {
+		if !p.Triggered() {
+			t.Fatalf("got: %v. wanted: the process to be triggered", p.Triggered())
+		}
+	}
+	if v, err := running.Value(); v != "running" || err != nil {
+		t.Fatalf("got: %v, %v. wanted: running, <nil>", v, err)
+	}
+	if env.Step() {
+		t.Fatal("got: true. wanted: no more events")
+	}
+}
+
+func TestEnvironment_Event(t *testing.T) {
+	env := sim.NewEnvironment()
+	ev := env.Event()
+
+	var got interface{}
+	env.Process(func(gc *generator.Controller) (interface{}, error) {
+		got, _, _ = gc.Yield(ev)
+		return nil, nil
+	})
+	env.Process(func(gc *generator.Controller) (interface{}, error) {
+		gc.Yield(env.Timeout(4, nil))
+		ev.Succeed("fired")
+		ev.Fail(fmt.Errorf("ignored"))
+		return nil, nil
+	})
+
+	if err := env.Run(); err != nil || got != "fired" || env.Now() != 4 {
+		t.Fatalf("got: %v, %v, %v. wanted: <nil>, fired, 4", err, got, env.Now())
+	}
+}
+
+func TestResource(t *testing.T) {
+	var trace []string
+	env := sim.NewEnvironment()
+	station := sim.NewResource(env, 2)
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprint("car", i)
+		env.Process(func(gc *generator.Controller) (interface{}, error) {
+			gc.Yield(station.Request())
+			trace = append(trace, fmt.Sprint(name, " charging@", env.Now()))
+			gc.Yield(env.Timeout(5, nil))
+			station.Release()
+			return nil, nil
+		})
+	}
+
+	if err := env.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"car0 charging@0", "car1 charging@0",
+		"car2 charging@5", "car3 charging@5",
+	}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("got: %v. wanted: %v", trace, want)
+	}
+	if station.Users() != 0 || station.Queued() != 0 {
+		t.Fatalf("got: %v, %v. wanted: 0, 0", station.Users(), station.Queued())
+	}
+}
+
+func TestStore(t *testing.T) {
+	var trace []string
+	env := sim.NewEnvironment()
+	store := sim.NewStore(env, 1)
+	env.Process(func(gc *generator.Controller) (interface{}, error) {
+		for i := 0; i < 3; i++ {
+			gc.Yield(store.Put(i))
+			trace = append(trace, fmt.Sprint("put ", i, "@", env.Now()))
+		}
+		return nil, nil
+	})
+	env.Process(func(gc *generator.Controller) (interface{}, error) {
+		for i := 0; i < 3; i++ {
+			gc.Yield(env.Timeout(2, nil))
+			v, _, _ := gc.Yield(store.Get())
+			trace = append(trace, fmt.Sprint("got ", v, "@", env.Now()))
+		}
+		return nil, nil
+	})
+
+	if err := env.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"put 0@0", "got 0@2", "put 1@2",
+		"got 1@4", "put 2@4", "got 2@6",
+	}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("got: %v. wanted: %v", trace, want)
+	}
+}
+
+func TestContainer(t *testing.T) {
+	var trace []string
+	env := sim.NewEnvironment()
+	tank := sim.NewContainer(env, 100, 10)
+	env.Process(func(gc *generator.Controller) (interface{}, error) {
+		gc.Yield(tank.Get(30))
+		trace = append(trace, fmt.Sprint("refueled@", env.Now()))
+		return nil, nil
+	})
+	env.Process(func(gc *generator.Controller) (interface{}, error) {
+		gc.Yield(env.Timeout(3, nil))
+		gc.Yield(tank.Put(25))
+		trace = append(trace, fmt.Sprint("filled@", env.Now()))
+		return nil, nil
+	})
+
+	if err := env.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"filled@3", "refueled@3"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Fatalf("got: %v. wanted: %v", trace, want)
+	}
+	if tank.Level() != 5 {
+		t.Fatalf("got: %v. wanted: 5", tank.Level())
+	}
+}
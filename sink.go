@@ -0,0 +1,112 @@
+package generator
+
+import "errors"
+
+// ErrSinkClosed is returned by `Sink#Send` when the sink has been closed
+// or its `Func` has already returned.
+var ErrSinkClosed = errors.New("generator: sink is closed")
+
+// Sink is a generator that consumes values instead of producing them.
+// Its `Func` asks for the next value by calling `Controller#Yield`,
+// whose yielded value is ignored, and receives the value passed to
+// `Sink#Send`. When the sink is closed, `Controller#Yield` returns
+// shouldReturn equal to true and the `Func` should return its result:
+//
+//	sum := generator.NewSink(
+//		func(gc *generator.Controller) (interface{}, error) {
+//			total := 0
+//			for {
+//				v, closed, _ := gc.Yield(nil)
+//				if closed {
+//					return total, nil
+//				}
+//				total += v.(int)
+//			}
+//		},
+//	)
+//
+// Sink implements `io.Writer` so that incremental parsers written as
+// sinks can be fed chunks of bytes. It's not safe for concurrent use.
+type Sink struct {
+	g       *Generator
+	started bool
+	isDone  bool
+	result  interface{}
+	err     error
+}
+
+// NewSink creates a sink whose values are consumed by the `Func`. The
+// `Func` is started when the first value is sent or when the sink is
+// closed.
+func NewSink(sinkFunc Func) *Sink {
+	return &Sink{g: New(sinkFunc)}
+}
+
+// Send passes the value to the `Func` and waits until it asks for the
+// next value. If the `Func` delivers an error using `Controller#Error`
+// instead of asking for the next value, the error is returned; the
+// `Func` receives the next value from `Controller#Error`.
+//
+// If the `Func` delivers an error before it asks for the first value,
+// the call that started it returns the error without passing the value;
+// the `Func` receives the value of the next call from
+// `Controller#Error`.
+//
+// If the `Func` returns after receiving the value, its result is kept
+// for `Close` and the error it returned, if any, is returned. Sending
+// values after that returns `ErrSinkClosed`.
+func (s *Sink) Send(value interface{}) error {
+	canReceive, err := s.start()
+	if err != nil {
+		return err
+	}
+	if !canReceive {
+		return ErrSinkClosed
+	}
+	result, isDone, err := s.g.Next(value)
+	if isDone {
+		s.finish(result, err)
+	}
+	return err
+}
+
+// Write sends a copy of p to the `Func` as a `[]byte`. It returns the
+// error returned by `Send`.
+func (s *Sink) Write(p []byte) (int, error) {
+	if err := s.Send(append([]byte(nil), p...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close tells the `Func` that there are no more values and returns its
+// result. Calling it more than once returns the same result.
+func (s *Sink) Close() (interface{}, error) {
+	if canReceive, _ := s.start(); canReceive {
+		result, _, err := s.g.Return(nil)
+		s.finish(result, err)
+	}
+	return s.result, s.err
+}
+
+// start runs the `Func` until it asks for the first value if it hasn't
+// been started yet. It reports whether the `Func` can still receive
+// values and returns the error the `Func` delivered instead of asking
+// for the first value.
+func (s *Sink) start() (bool, error) {
+	if !s.started {
+		s.started = true
+		value, isDone, err := s.g.Next(nil)
+		if isDone {
+			s.finish(value, err)
+		} else if err != nil {
+			return true, err
+		}
+	}
+	return !s.isDone, nil
+}
+
+func (s *Sink) finish(result interface{}, err error) {
+	s.isDone = true
+	s.result, s.err = result, err
+}
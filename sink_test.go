@@ -0,0 +1,177 @@
+package generator_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+)
+
+func TestSink(t *testing.T) {
+	t.Run(`Send(1),Send(2),Close()`, func(t *testing.T) {
+		s := generator.NewSink(sumSink)
+		if err := s.Send(1); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Send(2); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := s.Close(); v != 3 || err != nil {
+			t.Fatalf("got: %v, %v. wanted: 3, <nil>", v, err)
+		}
+		if v, err := s.Close(); v != 3 || err != nil {
+			t.Fatalf("got: %v, %v. wanted: 3, <nil>", v, err)
+		}
+		if err := s.Send(3); err != generator.ErrSinkClosed {
+			t.Fatalf("got: %v. wanted: %v", err, generator.ErrSinkClosed)
+		}
+	})
+	t.Run(`Close()`, func(t *testing.T) {
+		s := generator.NewSink(sumSink)
+		if v, err := s.Close(); v != 0 || err != nil {
+			t.Fatalf("got: %v, %v. wanted: 0, <nil>", v, err)
+		}
+	})
+	t.Run(`Func returns early`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		s := generator.NewSink(
+			func(gc *generator.Controller) (interface{}, error) {
+				v, _, _ := gc.Yield(nil)
+				return v, e1
+			},
+		)
+		if err := s.Send("a"); err != e1 {
+			t.Fatalf("got: %v. wanted: e1", err)
+		}
+		if err := s.Send("b"); err != generator.ErrSinkClosed {
+			t.Fatalf("got: %v. wanted: %v", err, generator.ErrSinkClosed)
+		}
+		if v, err := s.Close(); v != "a" || err != e1 {
+			t.Fatalf("got: %v, %v. wanted: a, e1", v, err)
+		}
+	})
+	t.Run(`Func doesn't ask for values`, func(t *testing.T) {
+		s := generator.NewSink(
+			func(gc *generator.Controller) (interface{}, error) {
+				return "r", nil
+			},
+		)
+		if err := s.Send("a"); err != generator.ErrSinkClosed {
+			t.Fatalf("got: %v. wanted: %v", err, generator.ErrSinkClosed)
+		}
+		if v, err := s.Close(); v != "r" || err != nil {
+			t.Fatalf("got: %v, %v. wanted: r, <nil>", v, err)
+		}
+	})
+	t.Run(`Controller.Error`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		s := generator.NewSink(
+			func(gc *generator.Controller) (interface{}, error) {
+				var received []interface{}
+				v, _, _ := gc.Yield(nil)
+				for {
+					if v == "bad" {
+						v, _, _ = gc.Error(e1)
+						continue
+					}
+					received = append(received, v)
+					var closed bool
+					if v, closed, _ = gc.Yield(nil); closed {
+						return received, nil
+					}
+				}
+			},
+		)
+		s.Send("a")
+		if err := s.Send("bad"); err != e1 {
+			t.Fatalf("got: %v. wanted: e1", err)
+		}
+		s.Send("b")
+		v, err := s.Close()
+		if want := []interface{}{"a", "b"}; !reflect.DeepEqual(v, want) || err != nil {
+			t.Fatalf("got: %v, %v. wanted: %v, <nil>", v, err, want)
+		}
+	})
+	t.Run(`Controller.Error before the first Yield`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		s := generator.NewSink(
+			func(gc *generator.Controller) (interface{}, error) {
+				var received []interface{}
+				v, _, _ := gc.Error(e1)
+				for {
+					received = append(received, v)
+					var closed bool
+					if v, closed, _ = gc.Yield(nil); closed {
+						return received, nil
+					}
+				}
+			},
+		)
+		if err := s.Send(1); err != e1 {
+			t.Fatalf("got: %v. wanted: e1", err)
+		}
+		if err := s.Send(2); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		v, err := s.Close()
+		if want := []interface{}{2}; !reflect.DeepEqual(v, want) || err != nil {
+			t.Fatalf("got: %v, %v. wanted: %v, <nil>", v, err, want)
+		}
+	})
+}
+
+func TestSink_Write(t *testing.T) {
+	// a line splitter that is fed chunks that don't end at line breaks
+	s := generator.NewSink(
+		func(gc *generator.Controller) (interface{}, error) {
+			var (
+				lines   []string
+				pending []byte
+			)
+			for {
+				v, closed, _ := gc.Yield(nil)
+				if closed {
+					if len(pending) > 0 {
+						lines = append(lines, string(pending))
+					}
+					return lines, nil
+				}
+				pending = append(pending, v.([]byte)...)
+				for {
+					i := bytes.IndexByte(pending, '\n')
+					if i < 0 {
+						break
+					}
+					lines = append(lines, string(pending[:i]))
+					pending = pending[i+1:]
+				}
+			}
+		},
+	)
+
+	var w io.Writer = s
+	r := strings.NewReader("first line\nsecond line\nthird")
+	if _, err := io.CopyBuffer(w, struct{ io.Reader }{r}, make([]byte, 4)); err != nil {
+		t.Fatal(err)
+	}
+	v, err := s.Close()
+	want := []string{"first line", "second line", "third"}
+	if !reflect.DeepEqual(v, want) || err != nil {
+		t.Fatalf("got: %v, %v. wanted: %v, <nil>", v, err, want)
+	}
+}
+
+func sumSink(gc *generator.Controller) (interface{}, error) {
+	total := 0
+	for {
+		v, closed, _ := gc.Yield(nil)
+		if closed {
+			return total, nil
+		}
+		total += v.(int)
+	}
+}
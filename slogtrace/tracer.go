@@ -0,0 +1,47 @@
+// Package slogtrace logs the events of generators using `log/slog`. It's
+// kept apart from the generator package so that the generator package
+// doesn't require the Go version that added `log/slog`.
+package slogtrace
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// Tracer is a `generator.Tracer` that logs each event using a
+// `*slog.Logger`.
+type Tracer struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+// New creates a tracer that logs the events to the logger at the level.
+func New(logger *slog.Logger, level slog.Level) *Tracer {
+	return &Tracer{logger: logger, level: level}
+}
+
+// Trace logs the event with its kind and generator ID, and its value and
+// error if they are not nil. The time of the log record is the time of
+// the event.
+func (t *Tracer) Trace(event generator.TraceEvent) {
+	ctx := context.Background()
+	handler := t.logger.Handler()
+	if !handler.Enabled(ctx, t.level) {
+		return
+	}
+
+	r := slog.NewRecord(event.Time, t.level, "generator trace", 0)
+	r.AddAttrs(
+		slog.String("kind", event.Kind.String()),
+		slog.Uint64("generator_id", event.GeneratorID),
+	)
+	if event.Value != nil {
+		r.AddAttrs(slog.Any("value", event.Value))
+	}
+	if event.Err != nil {
+		r.AddAttrs(slog.Any("error", event.Err))
+	}
+	handler.Handle(ctx, r)
+}
@@ -0,0 +1,46 @@
+package slogtrace_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/slogtrace"
+)
+
+func TestTracer(t *testing.T) {
+	var b bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&b, nil))
+	g := generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			gc.Yield("v1")
+			return nil, fmt.Errorf("e1")
+		},
+		generator.WithTracer(slogtrace.New(logger, slog.LevelInfo)),
+	)
+	g.Next(nil)
+	g.Next(nil)
+
+	out := b.String()
+	for _, want := range []string{
+		"kind=controller_yield", "value=v1", "kind=done", "error=e1",
+		fmt.Sprint("generator_id=", g.ID()),
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("got: %v. wanted it to contain %v", out, want)
+		}
+	}
+
+	b.Reset()
+	quiet := generator.New(
+		func(gc *generator.Controller) (interface{}, error) { return nil, nil },
+		generator.WithTracer(slogtrace.New(logger, slog.LevelDebug)),
+	)
+	quiet.Next(nil)
+	if b.Len() != 0 {
+		t.Fatalf("got: %v. wanted: nothing logged below the handler's level", b.String())
+	}
+}
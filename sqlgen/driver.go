@@ -0,0 +1,171 @@
+package sqlgen
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// driverRows is a `driver.Rows` backed by a generator.
+type driverRows struct {
+	columns []string
+	g       *generator.Generator
+	isDone  bool
+}
+
+// DriverRows returns a `driver.Rows` with the given columns whose rows
+// are the values yielded by g. Each value should be a `[]interface{}`
+// with a value for each of the columns. The values are converted using
+// `driver.DefaultParameterConverter` so types like `int` can be used.
+//
+// An error delivered by g is returned by the rows' `Next`. Closing the
+// rows before g is done stops it with `Generator#Return`.
+func DriverRows(columns []string, g *generator.Generator) driver.Rows {
+	return &driverRows{columns: columns, g: g}
+}
+
+func (r *driverRows) Columns() []string {
+	return r.columns
+}
+
+func (r *driverRows) Next(dest []driver.Value) error {
+	if r.isDone {
+		return io.EOF
+	}
+
+	value, isDone, err := r.g.Next(nil)
+	if isDone {
+		r.isDone = true
+		if err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	if err != nil {
+		return err
+	}
+
+	row, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("sqlgen: row must be []interface{}, got %T", value)
+	}
+	if len(row) != len(dest) {
+		return fmt.Errorf("sqlgen: row has %d values, expected %d", len(row), len(dest))
+	}
+	for i, v := range row {
+		dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			return fmt.Errorf("sqlgen: column %q: %w", r.columns[i], err)
+		}
+		dest[i] = dv
+	}
+	return nil
+}
+
+func (r *driverRows) Close() error {
+	if !r.isDone {
+		r.isDone = true
+		r.g.Return(nil)
+	}
+	return nil
+}
+
+// Table is a virtual table served by the driver used by `OpenDB`.
+type Table struct {
+	// Columns are the names of the table's columns.
+	Columns []string
+
+	// New creates the generator of the table's rows for a query. The
+	// rows are described in `DriverRows`. args are the arguments of the
+	// query.
+	New func(args []interface{}) (*generator.Generator, error)
+}
+
+// Tables maps the names of virtual tables to their definition.
+type Tables map[string]Table
+
+// OpenDB returns a `*sql.DB` whose queries are served by the tables.
+// The query is the name of a table, like `db.Query("numbers", 1, 10)`,
+// and its arguments are passed to the table's `New`. Only queries are
+// supported: `Exec` fails and so does `Begin`, so transactions can't be
+// used with the returned database.
+func OpenDB(tables Tables) *sql.DB {
+	return sql.OpenDB(&connector{tables: tables})
+}
+
+type connector struct {
+	tables Tables
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{tables: c.tables}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return tablesDriver{tables: c.tables}
+}
+
+type tablesDriver struct {
+	tables Tables
+}
+
+func (d tablesDriver) Open(string) (driver.Conn, error) {
+	return &conn{tables: d.tables}, nil
+}
+
+var errNotSupported = errors.New("sqlgen: not supported")
+
+type conn struct {
+	tables Tables
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	table, ok := c.tables[strings.TrimSpace(query)]
+	if !ok {
+		return nil, fmt.Errorf("sqlgen: no such table: %q", query)
+	}
+	return &stmt{table: table}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return nil, errNotSupported
+}
+
+type stmt struct {
+	table Table
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	// the number of arguments depends on the table
+	return -1
+}
+
+func (s *stmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errNotSupported
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg
+	}
+	g, err := s.table.New(values)
+	if err != nil {
+		return nil, err
+	}
+	return DriverRows(s.table.Columns, g), nil
+}
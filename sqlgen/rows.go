@@ -0,0 +1,70 @@
+// Package sqlgen connects generators with `database/sql`.
+//
+// `Rows` creates a generator that yields the rows of a query. In the
+// other direction, `DriverRows` exposes a generator as `driver.Rows` and
+// `OpenDB` serves generators as virtual tables through a small
+// `database/sql` driver.
+package sqlgen
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/bmdelacruz/generator"
+)
+
+// Querier is implemented by `*sql.DB`, `*sql.Conn` and `*sql.Tx`.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// ScanFunc scans the current row of rows into a value.
+type ScanFunc func(rows *sql.Rows) (interface{}, error)
+
+// Rows creates a generator that runs the query with args and yields the
+// value returned by scan for each of the resulting rows.
+//
+// A row that can't be scanned is delivered as `Generator#Next`'s error
+// and the generator continues with the next row. The rows are closed
+// when the generator is done, including when the consumer calls
+// `Generator#Return` or `Generator#Error`, which make the `Func` return
+// the value or the error passed to them.
+//
+// The query is only run after the first `Generator#Next` call. An error
+// from running the query or from iterating the rows stops the generator.
+func Rows(
+	ctx context.Context,
+	db Querier,
+	query string,
+	args []interface{},
+	scan ScanFunc,
+) *generator.Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			rows, err := db.QueryContext(ctx, query, args...)
+			if err != nil {
+				return nil, err
+			}
+			defer rows.Close()
+
+			for rows.Next() {
+				var (
+					value        interface{}
+					shouldReturn bool
+				)
+				if v, serr := scan(rows); serr != nil {
+					value, shouldReturn, err = gc.Error(serr)
+				} else {
+					value, shouldReturn, err = gc.Yield(v)
+				}
+
+				if shouldReturn {
+					return value, nil
+				} else if err != nil {
+					return nil, err
+				}
+			}
+			return nil, rows.Err()
+		},
+	)
+}
@@ -0,0 +1,195 @@
+package sqlgen_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+	"github.com/bmdelacruz/generator/sqlgen"
+)
+
+// openTestDB opens a database with a "numbers" table that has the rows
+// from the first argument up to, but not including, the second argument.
+// stopped is closed when the table's generator is stopped early.
+func openTestDB(stopped chan struct{}) *sql.DB {
+	return sqlgen.OpenDB(sqlgen.Tables{
+		"numbers": {
+			Columns: []string{"n", "label"},
+			New: func(args []interface{}) (*generator.Generator, error) {
+				if len(args) != 2 {
+					return nil, fmt.Errorf("numbers: expected 2 arguments, got %d", len(args))
+				}
+				from, to := args[0].(int64), args[1].(int64)
+				return generator.New(
+					func(gc *generator.Controller) (interface{}, error) {
+						for i := from; i < to; i++ {
+							if i < 0 {
+								gc.Error(fmt.Errorf("negative: %d", i))
+								continue
+							}
+							_, shouldReturn, _ := gc.Yield([]interface{}{i, fmt.Sprint("#", i)})
+							if shouldReturn {
+								close(stopped)
+								return nil, nil
+							}
+						}
+						return nil, nil
+					},
+				), nil
+			},
+		},
+	})
+}
+
+type number struct {
+	N     int
+	Label string
+}
+
+func scanNumber(rows *sql.Rows) (interface{}, error) {
+	var n number
+	err := rows.Scan(&n.N, &n.Label)
+	return n, err
+}
+
+func TestRows(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run(`all rows`, func(t *testing.T) {
+		db := openTestDB(make(chan struct{}))
+		defer db.Close()
+
+		g := sqlgen.Rows(ctx, db, "numbers", []interface{}{1, 4}, scanNumber)
+		var got []interface{}
+		for v, isDone, err := g.Next(nil); !isDone; v, isDone, err = g.Next(nil) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, v)
+		}
+		want := []interface{}{number{1, "#1"}, number{2, "#2"}, number{3, "#3"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got: %v. wanted: %v", got, want)
+		}
+	})
+	t.Run(`Return("r") closes the rows`, func(t *testing.T) {
+		stopped := make(chan struct{})
+		db := openTestDB(stopped)
+		defer db.Close()
+
+		g := sqlgen.Rows(ctx, db, "numbers", []interface{}{1, 100}, scanNumber)
+		testWith(t).expect(g.Next(nil)).toReturn(number{1, "#1"}, false, nil)
+		testWith(t).expect(g.Return("r")).toReturn("r", true, nil)
+
+		select {
+		case <-stopped:
+		default:
+			t.Fatal("the table's generator was not stopped")
+		}
+		if inUse := db.Stats().InUse; inUse != 0 {
+			t.Fatalf("got: %v. wanted: 0 connections in use", inUse)
+		}
+	})
+	t.Run(`error from the driver rows`, func(t *testing.T) {
+		db := openTestDB(make(chan struct{}))
+		defer db.Close()
+
+		g := sqlgen.Rows(ctx, db, "numbers", []interface{}{-1, 1}, scanNumber)
+		_, isDone, err := g.Next(nil)
+		if !isDone || err == nil || err.Error() != "negative: -1" {
+			t.Fatalf("got: %v, %v. wanted: true, negative: -1", isDone, err)
+		}
+	})
+	t.Run(`scan error`, func(t *testing.T) {
+		db := openTestDB(make(chan struct{}))
+		defer db.Close()
+
+		scanLabelAsInt := func(rows *sql.Rows) (interface{}, error) {
+			var n, label int
+			err := rows.Scan(&n, &label)
+			return n, err
+		}
+		g := sqlgen.Rows(ctx, db, "numbers", []interface{}{1, 3}, scanLabelAsInt)
+		if _, isDone, err := g.Next(nil); isDone || err == nil {
+			t.Fatalf("got: %v, %v. wanted: false, a scan error", isDone, err)
+		}
+		if _, isDone, err := g.Next(nil); isDone || err == nil {
+			t.Fatalf("got: %v, %v. wanted: false, a scan error", isDone, err)
+		}
+		testWith(t).expect(g.Next(nil)).toReturn(nil, true, nil)
+	})
+	t.Run(`query error`, func(t *testing.T) {
+		db := openTestDB(make(chan struct{}))
+		defer db.Close()
+
+		g := sqlgen.Rows(ctx, db, "missing", nil, scanNumber)
+		if _, isDone, err := g.Next(nil); !isDone || err == nil {
+			t.Fatalf("got: %v, %v. wanted: true, an error", isDone, err)
+		}
+	})
+}
+
+func TestDriverRows_Close(t *testing.T) {
+	stopped := make(chan struct{})
+	db := openTestDB(stopped)
+	defer db.Close()
+
+	rows, err := db.Query("numbers", 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rows.Next() {
+		t.Fatal(rows.Err())
+	}
+	rows.Close()
+
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("the table's generator was not stopped")
+	}
+}
+
+func TestOpenDB_Begin(t *testing.T) {
+	db := openTestDB(make(chan struct{}))
+	defer db.Close()
+
+	if tx, err := db.Begin(); err == nil {
+		tx.Rollback()
+		t.Fatal("got: <nil>. wanted: an error")
+	}
+}
+
+// utility stuff =====================================================
+
+type tw struct {
+	t *testing.T
+}
+
+type twe struct {
+	tw *tw
+	v  interface{}
+	r  bool
+	e  error
+}
+
+func testWith(t *testing.T) *tw {
+	return &tw{t}
+}
+
+func (tw *tw) expect(v interface{}, r bool, e error) *twe {
+	return &twe{tw, v, r, e}
+}
+
+func (twe *twe) toReturn(v interface{}, r bool, e error) {
+	if v != twe.v || r != twe.r || e != twe.e {
+		twe.tw.t.Helper()
+		twe.tw.t.Fatalf(
+			"got: %v, %v, %v. wanted: %v, %v, %v",
+			twe.v, twe.r, twe.e, v, r, e,
+		)
+	}
+}
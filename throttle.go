@@ -0,0 +1,280 @@
+package generator
+
+import (
+	"context"
+	"time"
+)
+
+// Throttle creates a generator that passes each call of the consumer to
+// g, delaying the `Generator#Next` calls so that, on average, at most
+// rate values per second are pulled from g. A token bucket of the size
+// burst allows up to burst calls to be made right away after a period
+// of inactivity.
+//
+// `Generator#Return` and `Generator#Error` are passed to g without any
+// delay. If ctx is done while waiting, g is stopped with
+// `Generator#Return` and the generator returns the context's error. A
+// nil clock means that the `SystemClock` is used.
+//
+// Throttle panics if rate isn't positive.
+func Throttle(ctx context.Context, g *Generator, rate float64, burst int, clock Clock) *Generator {
+	if !(rate > 0) {
+		panic("generator: non-positive rate for Throttle")
+	}
+	clock = clockOrSystem(clock)
+	if burst < 1 {
+		burst = 1
+	}
+	return New(
+		func(gc *Controller) (interface{}, error) {
+			tokens := float64(burst)
+			last := clock.Now()
+
+			// take waits until there's a token in the bucket and takes it
+			take := func() error {
+				for {
+					// refill the bucket with the tokens that accumulated
+					// since the last refill
+					now := clock.Now()
+					tokens += now.Sub(last).Seconds() * rate
+					if tokens > float64(burst) {
+						tokens = float64(burst)
+					}
+					last = now
+
+					if tokens >= 1 {
+						tokens--
+						return nil
+					}
+
+					wait := time.Duration((1 - tokens) / rate * float64(time.Second))
+					select {
+					case <-clock.After(wait):
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+			}
+
+			if err := take(); err != nil {
+				g.Return(nil)
+				return nil, err
+			}
+			value, isDone, err := g.Next(nil)
+			for !isDone {
+				sendValue, shouldReturn, thrownErr := forward(gc, value, err)
+				switch {
+				case shouldReturn:
+					value, isDone, err = g.Return(sendValue)
+				case thrownErr != nil:
+					value, isDone, err = g.Error(thrownErr)
+				default:
+					if terr := take(); terr != nil {
+						g.Return(nil)
+						return nil, terr
+					}
+					value, isDone, err = g.Next(sendValue)
+				}
+			}
+			return value, err
+		},
+	)
+}
+
+// Debounce creates a generator that only yields a value of g after the
+// duration has passed without g yielding another value. When g is done,
+// the last value that hasn't been yielded yet is yielded right away.
+//
+// Values are pulled from g in the background as soon as it yields
+// them, so the values passed to `Generator#Next` are not passed to g.
+// Errors delivered by g are passed to the consumer right away. The
+// consumer's `Generator#Return` and `Generator#Error` stop g: they are
+// passed to g and, if g yields another value after receiving the error,
+// it's stopped with `Generator#Return`. If ctx is done while waiting, g
+// is stopped with `Generator#Return` and the generator returns the
+// context's error. A nil clock means that the `SystemClock` is used.
+//
+// Debounce panics if d is negative.
+func Debounce(ctx context.Context, g *Generator, d time.Duration, clock Clock) *Generator {
+	if d < 0 {
+		panic("generator: negative duration for Debounce")
+	}
+	clock = clockOrSystem(clock)
+	return New(
+		func(gc *Controller) (interface{}, error) {
+			var (
+				latest    interface{}
+				hasLatest bool
+			)
+			pending := nextAsync(g)
+			for {
+				var timer <-chan time.Time
+				if hasLatest {
+					timer = clock.After(d)
+				}
+
+				select {
+				case res := <-pending:
+					if res.isDone {
+						if hasLatest {
+							if v, shouldReturn, thrownErr := gc.Yield(latest); shouldReturn {
+								return v, nil
+							} else if thrownErr != nil {
+								return nil, thrownErr
+							}
+						}
+						return res.value, res.err
+					}
+					if res.err != nil {
+						if ret, ok := passError(gc, g, res.err); ok {
+							return ret.value, ret.err
+						}
+					} else {
+						latest, hasLatest = res.value, true
+					}
+					pending = nextAsync(g)
+				case <-timer:
+					hasLatest = false
+					if ret, ok := passValue(gc, g, pending, latest); ok {
+						return ret.value, ret.err
+					}
+				case <-ctx.Done():
+					return stopAsync(g, pending, ctx.Err())
+				}
+			}
+		},
+	)
+}
+
+// Sample creates a generator that yields the latest value of g once per
+// period, skipping the periods in which g hasn't yielded a new value.
+// The values of g that were yielded after the last period when it's done
+// are not yielded.
+//
+// Values are pulled from g in the background like in `Debounce`, and
+// the consumer's calls, errors and ctx are handled in the same way.
+//
+// Sample panics if period isn't positive.
+func Sample(ctx context.Context, g *Generator, period time.Duration, clock Clock) *Generator {
+	if period <= 0 {
+		panic("generator: non-positive period for Sample")
+	}
+	clock = clockOrSystem(clock)
+	return New(
+		func(gc *Controller) (interface{}, error) {
+			var (
+				latest    interface{}
+				hasLatest bool
+			)
+			pending := nextAsync(g)
+			tick := clock.After(period)
+			for {
+				select {
+				case res := <-pending:
+					if res.isDone {
+						return res.value, res.err
+					}
+					if res.err != nil {
+						if ret, ok := passError(gc, g, res.err); ok {
+							return ret.value, ret.err
+						}
+					} else {
+						latest, hasLatest = res.value, true
+					}
+					pending = nextAsync(g)
+				case <-tick:
+					if hasLatest {
+						hasLatest = false
+						if ret, ok := passValue(gc, g, pending, latest); ok {
+							return ret.value, ret.err
+						}
+					}
+					tick = clock.After(period)
+				case <-ctx.Done():
+					return stopAsync(g, pending, ctx.Err())
+				}
+			}
+		},
+	)
+}
+
+type nextResult struct {
+	value  interface{}
+	isDone bool
+	err    error
+}
+
+// nextAsync calls `Next` with a nil value from another goroutine and
+// returns a channel that receives its result.
+func nextAsync(g *Generator) <-chan nextResult {
+	resultChan := make(chan nextResult, 1)
+	go func() {
+		value, isDone, err := g.Next(nil)
+		resultChan <- nextResult{value, isDone, err}
+	}()
+	return resultChan
+}
+
+// stopAsync waits for the pending `Next` call of g to return and then
+// stops g with `Return` if it isn't done. It returns (<nil>, err).
+func stopAsync(g *Generator, pending <-chan nextResult, err error) (interface{}, error) {
+	if res := <-pending; !res.isDone {
+		g.Return(nil)
+	}
+	return nil, err
+}
+
+// passValue yields the value to the consumer. If the consumer calls
+// `Return` or `Error` instead of `Next`, g is stopped after its pending
+// `Next` call returns and its result is returned with true.
+func passValue(gc *Controller, g *Generator, pending <-chan nextResult, value interface{}) (nextResult, bool) {
+	sendValue, shouldReturn, thrownErr := gc.Yield(value)
+	return passStop(g, pending, sendValue, shouldReturn, thrownErr)
+}
+
+// passError delivers the error to the consumer. It's called right after
+// the `Next` call of g has returned the error so g can be stopped right
+// away if the consumer calls `Return` or `Error`.
+func passError(gc *Controller, g *Generator, err error) (nextResult, bool) {
+	sendValue, shouldReturn, thrownErr := gc.Error(err)
+	return passStop(g, nil, sendValue, shouldReturn, thrownErr)
+}
+
+func passStop(
+	g *Generator,
+	pending <-chan nextResult,
+	sendValue interface{},
+	shouldReturn bool,
+	thrownErr error,
+) (nextResult, bool) {
+	if !shouldReturn && thrownErr == nil {
+		return nextResult{}, false
+	}
+	if pending != nil {
+		if res := <-pending; res.isDone {
+			return res, true
+		}
+	}
+
+	var res nextResult
+	if shouldReturn {
+		res.value, res.isDone, res.err = g.Return(sendValue)
+	} else {
+		res.value, res.isDone, res.err = g.Error(thrownErr)
+		for !res.isDone {
+			// g handled the error. it has to be stopped anyway since the
+			// consumer has stopped pulling values.
+			res.value, res.isDone, res.err = g.Return(nil)
+		}
+	}
+	return res, true
+}
+
+// forward yields the value to the consumer, or delivers the error if
+// it's not nil, and returns what the consumer passed back.
+func forward(gc *Controller, value interface{}, err error) (interface{}, bool, error) {
+	if err != nil {
+		return gc.Error(err)
+	}
+	return gc.Yield(value)
+}
@@ -0,0 +1,285 @@
+package generator_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/generator"
+)
+
+func TestThrottle(t *testing.T) {
+	t.Run(`token bucket`, func(t *testing.T) {
+		clock := newManualClock()
+		results := nextAll(generator.Throttle(context.Background(), count(5), 2, 2, clock))
+
+		// the burst of 2 is used right away
+		for _, want := range []interface{}{0, 1} {
+			if res := <-results; res.value != want || res.isDone {
+				t.Fatalf("got: %v, %v. wanted: %v, false", res.value, res.isDone, want)
+			}
+		}
+		// then the bucket refills at 2 tokens per second. the last call
+		// that finds out that the generator is done also needs a token.
+		for i, want := range []interface{}{2, 3, 4, nil} {
+			clock.waitForTimers(i + 1)
+			clock.advance(500 * time.Millisecond)
+			if res := <-results; res.value != want || res.isDone != (want == nil) {
+				t.Fatalf("got: %v, %v. wanted: %v, %v", res.value, res.isDone, want, want == nil)
+			}
+		}
+	})
+	t.Run(`non-positive rate`, func(t *testing.T) {
+		for _, rate := range []float64{0, -1, math.NaN()} {
+			expectPanic(t, "generator: non-positive rate for Throttle", func() {
+				generator.Throttle(context.Background(), count(1), rate, 1, newManualClock())
+			})
+		}
+	})
+	t.Run(`passes calls through`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		g := generator.Throttle(context.Background(), generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				v, _, _ := gc.Yield(1)
+				_, _, err := gc.Yield(v)
+				v, _, _ = gc.Yield(err)
+				return v, nil
+			},
+		), 1, 10, newManualClock())
+		testWith(t).expect(g.Next(nil)).toReturn(1, false, nil)
+		testWith(t).expect(g.Next("a")).toReturn("a", false, nil)
+		testWith(t).expect(g.Error(e1)).toReturn(e1, false, nil)
+		testWith(t).expect(g.Return("r")).toReturn("r", true, nil)
+	})
+	t.Run(`context cancellation`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		clock := newManualClock()
+		stopped := make(chan struct{})
+		g := generator.Throttle(ctx, generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				for {
+					if _, shouldReturn, _ := gc.Yield(nil); shouldReturn {
+						close(stopped)
+						return nil, nil
+					}
+				}
+			},
+		), 1, 1, clock)
+		g.Next(nil)
+
+		go func() {
+			clock.waitForTimers(1)
+			cancel()
+		}()
+		if _, isDone, err := g.Next(nil); !isDone || err != context.Canceled {
+			t.Fatalf("got: %v, %v. wanted: true, %v", isDone, err, context.Canceled)
+		}
+		<-stopped
+	})
+}
+
+func TestDebounce(t *testing.T) {
+	input, ack := make(chan interface{}), make(chan struct{})
+	clock := newManualClock()
+	g := generator.Debounce(context.Background(), fromChan(input, ack), time.Second, clock)
+	results := nextAll(g)
+
+	send(input, ack, "a")
+	clock.waitForTimers(1)
+	clock.advance(500 * time.Millisecond)
+	send(input, ack, "b")
+	clock.waitForTimers(2)
+	clock.advance(time.Second)
+	if res := <-results; res.value != "b" {
+		t.Fatalf("got: %v. wanted: b", res.value)
+	}
+
+	send(input, ack, "c")
+	close(input)
+	if res := <-results; res.value != "c" || res.isDone {
+		t.Fatalf("got: %v, %v. wanted: c, false", res.value, res.isDone)
+	}
+	if res := <-results; !res.isDone {
+		t.Fatalf("got: %v. wanted: true", res.isDone)
+	}
+
+	expectPanic(t, "generator: negative duration for Debounce", func() {
+		generator.Debounce(context.Background(), count(1), -time.Second, newManualClock())
+	})
+}
+
+func TestSample(t *testing.T) {
+	input, ack := make(chan interface{}), make(chan struct{})
+	clock := newManualClock()
+	g := generator.Sample(context.Background(), fromChan(input, ack), time.Second, clock)
+	results := nextAll(g)
+
+	clock.waitForTimers(1)
+	send(input, ack, "a")
+	send(input, ack, "b")
+	clock.advance(time.Second)
+	if res := <-results; res.value != "b" {
+		t.Fatalf("got: %v. wanted: b", res.value)
+	}
+
+	// no new value during this period
+	clock.waitForTimers(2)
+	clock.advance(time.Second)
+	clock.waitForTimers(3)
+	send(input, ack, "c")
+	clock.advance(time.Second)
+	if res := <-results; res.value != "c" {
+		t.Fatalf("got: %v. wanted: c", res.value)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g = generator.Sample(ctx, count(3), time.Second, newManualClock())
+	cancel()
+	if _, isDone, err := g.Next(nil); !isDone || err != context.Canceled {
+		t.Fatalf("got: %v, %v. wanted: true, %v", isDone, err, context.Canceled)
+	}
+
+	for _, period := range []time.Duration{0, -time.Second} {
+		expectPanic(t, "generator: non-positive period for Sample", func() {
+			generator.Sample(context.Background(), count(1), period, newManualClock())
+		})
+	}
+}
+
+// expectPanic fails the test if fn doesn't panic with the message.
+func expectPanic(t *testing.T, msg string, fn func()) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != msg {
+			t.Fatalf("got: %v. wanted: a panic with %q", r, msg)
+		}
+	}()
+	fn()
+}
+
+// count creates a generator that yields the numbers from 0 until n.
+func count(n int) *generator.Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			for i := 0; i < n; i++ {
+				if _, shouldReturn, _ := gc.Yield(i); shouldReturn {
+					return nil, nil
+				}
+			}
+			return nil, nil
+		},
+	)
+}
+
+// fromChan creates a generator that yields the values received from
+// input until it's closed. It sends to ack after each yielded value is
+// taken by the consumer.
+func fromChan(input <-chan interface{}, ack chan<- struct{}) *generator.Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			for v := range input {
+				if _, shouldReturn, _ := gc.Yield(v); shouldReturn {
+					return nil, nil
+				}
+				ack <- struct{}{}
+			}
+			return nil, nil
+		},
+	)
+}
+
+// send sends the value to the generator created by `fromChan` and waits
+// until it's taken.
+func send(input chan<- interface{}, ack <-chan struct{}, v interface{}) {
+	input <- v
+	<-ack
+}
+
+type result struct {
+	value  interface{}
+	isDone bool
+	err    error
+}
+
+// nextAll calls `Next` from another goroutine until g is done and sends
+// the results to the returned channel.
+func nextAll(g *generator.Generator) <-chan result {
+	results := make(chan result)
+	go func() {
+		for {
+			v, isDone, err := g.Next(nil)
+			results <- result{v, isDone, err}
+			if isDone {
+				return
+			}
+		}
+	}()
+	return results
+}
+
+// manualClock is a `generator.Clock` whose time only moves when it's
+// advanced.
+type manualClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	created int
+	timers  []manualTimer
+}
+
+type manualTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newManualClock() *manualClock {
+	c := &manualClock{}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, manualTimer{c.now.Add(d), ch})
+	c.created++
+	c.cond.Broadcast()
+	return ch
+}
+
+// advance moves the time forward and fires the timers that are due.
+func (c *manualClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	remaining := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			remaining = append(remaining, timer)
+		} else {
+			timer.ch <- c.now
+		}
+	}
+	c.timers = remaining
+}
+
+// waitForTimers waits until n timers have been created in total.
+func (c *manualClock) waitForTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.created < n {
+		c.cond.Wait()
+	}
+}
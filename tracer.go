@@ -0,0 +1,137 @@
+package generator
+
+import (
+	"sync"
+	"time"
+)
+
+// TraceKind identifies what happened in a `TraceEvent`.
+type TraceKind int
+
+const (
+	// TraceStart is reported when the `Func` starts running.
+	TraceStart TraceKind = iota
+
+	// TraceControllerYield is reported when the `Func` calls
+	// `Controller#Yield`. The event's value is the yielded value.
+	TraceControllerYield
+
+	// TraceControllerError is reported when the `Func` calls
+	// `Controller#Error`. The event's error is the delivered error.
+	TraceControllerError
+
+	// TraceSend is reported when a controller function returns to the
+	// `Func`. The event's value and error are the ones it returned.
+	TraceSend
+
+	// TraceGeneratorNext is reported when the consumer calls
+	// `Generator#Next`. The event's value is the argument.
+	TraceGeneratorNext
+
+	// TraceGeneratorReturn is reported when the consumer calls
+	// `Generator#Return`. The event's value is the argument.
+	TraceGeneratorReturn
+
+	// TraceGeneratorError is reported when the consumer calls
+	// `Generator#Error`. The event's error is the argument.
+	TraceGeneratorError
+
+	// TraceDone is reported when the `Func` returns. The event's value
+	// and error are the ones that will be received by the consumer.
+	TraceDone
+
+	// TracePanic is reported when the `Func` panics. The event's value
+	// is the recovered value. The panic continues after it's reported.
+	TracePanic
+)
+
+var traceKindNames = [...]string{
+	TraceStart:           "start",
+	TraceControllerYield: "controller_yield",
+	TraceControllerError: "controller_error",
+	TraceSend:            "send",
+	TraceGeneratorNext:   "generator_next",
+	TraceGeneratorReturn: "generator_return",
+	TraceGeneratorError:  "generator_error",
+	TraceDone:            "done",
+	TracePanic:           "panic",
+}
+
+func (k TraceKind) String() string {
+	if k >= 0 && int(k) < len(traceKindNames) {
+		return traceKindNames[k]
+	}
+	return "unknown"
+}
+
+// TraceEvent describes something that happened in a generator.
+type TraceEvent struct {
+	Kind        TraceKind
+	GeneratorID uint64
+	Time        time.Time
+	Value       interface{}
+	Err         error
+}
+
+// Tracer receives the events of the generators it was passed to using
+// `WithTracer`. Trace is called synchronously from the goroutine of the
+// consumer or of the `Func`, so it should return quickly. A tracer that
+// is shared by generators used from different goroutines should be safe
+// for concurrent use.
+type Tracer interface {
+	Trace(event TraceEvent)
+}
+
+// NopTracer is a `Tracer` that ignores all events. It's the default
+// tracer of a generator.
+type NopTracer struct{}
+
+// Trace does nothing.
+func (NopTracer) Trace(TraceEvent) {}
+
+// TraceRecorder is a `Tracer` that keeps the events in memory. It's
+// meant for tests. It's safe for concurrent use.
+type TraceRecorder struct {
+	mu     sync.Mutex
+	events []TraceEvent
+}
+
+// Trace records the event.
+func (r *TraceRecorder) Trace(event TraceEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Events returns the recorded events in the order they were reported.
+func (r *TraceRecorder) Events() []TraceEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]TraceEvent(nil), r.events...)
+}
+
+// Kinds returns the kinds of the recorded events in the order they were
+// reported.
+func (r *TraceRecorder) Kinds() []TraceKind {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kinds := make([]TraceKind, len(r.events))
+	for i, e := range r.events {
+		kinds[i] = e.Kind
+	}
+	return kinds
+}
+
+// trace reports the event to the generator's tracer, if it has one.
+func (g *state) trace(kind TraceKind, value interface{}, err error) {
+	if g.tracer == nil {
+		return
+	}
+	g.tracer.Trace(TraceEvent{
+		Kind:        kind,
+		GeneratorID: g.id,
+		Time:        time.Now(),
+		Value:       value,
+		Err:         err,
+	})
+}
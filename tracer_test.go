@@ -0,0 +1,115 @@
+package generator_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+)
+
+func TestTracer(t *testing.T) {
+	t.Run(`Next("a"),Error(<e1>),Return("c")|Yield(1),Error(<e2>)`, func(t *testing.T) {
+		e1 := fmt.Errorf("e1")
+		e2 := fmt.Errorf("e2")
+		rec := &generator.TraceRecorder{}
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				gc.Yield(1)
+				gc.Error(e2)
+				return 0, nil
+			},
+			generator.WithTracer(rec),
+		)
+		g.Next("a")
+		g.Error(e1)
+		g.Return("c")
+
+		want := []generator.TraceKind{
+			generator.TraceGeneratorNext,
+			generator.TraceStart,
+			generator.TraceControllerYield,
+			generator.TraceGeneratorError,
+			generator.TraceSend,
+			generator.TraceControllerError,
+			generator.TraceGeneratorReturn,
+			generator.TraceSend,
+			generator.TraceDone,
+		}
+		if !reflect.DeepEqual(rec.Kinds(), want) {
+			t.Fatalf("got: %v. wanted: %v", rec.Kinds(), want)
+		}
+
+		events := rec.Events()
+		for _, e := range events {
+			if e.GeneratorID != g.ID() || e.Time.IsZero() {
+				t.Fatalf("got: %v, %v. wanted: %v, a timestamp", e.GeneratorID, e.Time, g.ID())
+			}
+		}
+		if e := events[2]; e.Value != 1 {
+			t.Fatalf("got: %v. wanted: 1", e.Value)
+		}
+		if e := events[4]; e.Err != e1 {
+			t.Fatalf("got: %v. wanted: e1", e.Err)
+		}
+		if e := events[7]; e.Value != "c" {
+			t.Fatalf("got: %v. wanted: c", e.Value)
+		}
+		if e := events[8]; e.Value != 0 || e.Err != nil {
+			t.Fatalf("got: %v, %v. wanted: 0, <nil>", e.Value, e.Err)
+		}
+	})
+	t.Run(`NopTracer`, func(t *testing.T) {
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				gc.Yield(1)
+				return 0, nil
+			},
+			generator.WithTracer(generator.NopTracer{}),
+		)
+		testWith(t).expect(g.Next(nil)).toReturn(1, false, nil)
+		testWith(t).expect(g.Next(nil)).toReturn(0, true, nil)
+	})
+	t.Run(`unique IDs`, func(t *testing.T) {
+		g1 := generator.New(func(gc *generator.Controller) (interface{}, error) { return nil, nil })
+		g2 := generator.New(func(gc *generator.Controller) (interface{}, error) { return nil, nil })
+		if g1.ID() == g2.ID() {
+			t.Fatalf("got: %v, %v. wanted: different IDs", g1.ID(), g2.ID())
+		}
+	})
+}
+
+func TestTracer_Panic(t *testing.T) {
+	if os.Getenv("GENERATOR_TEST_PANIC") == "1" {
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				panic("boom")
+			},
+			generator.WithTracer(printTracer{}),
+		)
+		g.Next(nil)
+		return
+	}
+
+	// the panic crashes the program so it has to be run in another process
+	cmd := exec.Command(os.Args[0], "-test.run=^TestTracer_Panic$")
+	cmd.Env = append(os.Environ(), "GENERATOR_TEST_PANIC=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatal("got: <nil>. wanted: the process to crash")
+	}
+	if !strings.Contains(string(out), "kind=panic generator_id=1 value=boom") ||
+		!strings.Contains(string(out), "panic: boom") {
+		t.Fatalf("got: %s. wanted: the panic to be traced and rethrown", out)
+	}
+}
+
+// printTracer prints the events to the standard output.
+type printTracer struct{}
+
+func (printTracer) Trace(event generator.TraceEvent) {
+	fmt.Printf("kind=%v generator_id=%v value=%v\n", event.Kind, event.GeneratorID, event.Value)
+}
@@ -0,0 +1,258 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StallKind tells which side of a generator a `Stall` is about.
+type StallKind int
+
+const (
+	// StallAbandonedConsumer is reported when the consumer hasn't
+	// called any of the generator functions for longer than the
+	// threshold while the `Func` is waiting for it, either in a
+	// controller function or before it has started.
+	StallAbandonedConsumer StallKind = iota
+
+	// StallStalledProducer is reported when the consumer has been
+	// blocked in a generator function for longer than the threshold
+	// because the `Func` hasn't called a controller function or
+	// returned.
+	StallStalledProducer
+)
+
+var stallKindNames = [...]string{
+	StallAbandonedConsumer: "abandoned_consumer",
+	StallStalledProducer:   "stalled_producer",
+}
+
+// String returns the name of the kind, like "stalled_producer".
+func (k StallKind) String() string {
+	if k < 0 || int(k) >= len(stallKindNames) {
+		return fmt.Sprintf("StallKind(%d)", int(k))
+	}
+	return stallKindNames[k]
+}
+
+// Stall describes a generator that a `Watchdog` found stuck.
+type Stall struct {
+	Kind        StallKind
+	GeneratorID uint64
+	Name        string
+
+	// Since is when the side that's waiting started to wait, and
+	// Duration is how long it has been waiting when it was reported.
+	Since    time.Time
+	Duration time.Duration
+
+	// ProducerStack is the stack of the goroutine of the `Func` in the
+	// format of `runtime.Stack`.
+	ProducerStack string
+
+	// ConsumerStack is the stack of the goroutine that's blocked in the
+	// generator function. It's only set for `StallStalledProducer`.
+	ConsumerStack string
+}
+
+// Watchdog reports the generators that were created using
+// `WithWatchdog` and that got stuck on one side for longer than its
+// threshold. Each time a side gets stuck, it's reported only once.
+type Watchdog struct {
+	threshold time.Duration
+	clock     Clock
+	onStall   func(Stall)
+
+	mu         sync.Mutex
+	generators map[uint64]*watched
+}
+
+// watched is what the watchdog knows about a generator.
+type watched struct {
+	name        string
+	kind        StallKind
+	since       time.Time
+	reported    bool
+	producerGID uint64
+	consumerGID uint64
+}
+
+// NewWatchdog creates a watchdog that calls onStall with the generators
+// that got stuck for longer than threshold. A nil clock means that the
+// `SystemClock` is used. The generators are checked when `Check` is
+// called or while `Run` is running.
+//
+// NewWatchdog panics if threshold isn't positive.
+func NewWatchdog(threshold time.Duration, onStall func(Stall), clock Clock) *Watchdog {
+	if threshold <= 0 {
+		panic("generator: non-positive threshold for NewWatchdog")
+	}
+	return &Watchdog{
+		threshold:  threshold,
+		clock:      clockOrSystem(clock),
+		onStall:    onStall,
+		generators: make(map[uint64]*watched),
+	}
+}
+
+// WithWatchdog makes the watchdog watch the generator until its `Func`
+// returns. Watching a generator makes each call of its generator
+// functions look up the ID of the calling goroutine.
+func WithWatchdog(watchdog *Watchdog) Option {
+	return func(g *Generator) {
+		g.watchdog = watchdog
+	}
+}
+
+// Run checks the generators twice per threshold until ctx is done. It
+// returns the context's error.
+func (w *Watchdog) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-w.clock.After(w.threshold / 2):
+			w.Check()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Check calls the callback of the watchdog with the generators that
+// have been stuck for longer than the threshold and that haven't been
+// reported yet. It returns the number of reported generators.
+func (w *Watchdog) Check() int {
+	now := w.clock.Now()
+
+	var stalls []Stall
+	var gids []uint64
+	w.mu.Lock()
+	for id, wg := range w.generators {
+		if wg.reported || now.Sub(wg.since) < w.threshold {
+			continue
+		}
+		wg.reported = true
+		stalls = append(stalls, Stall{
+			Kind:        wg.kind,
+			GeneratorID: id,
+			Name:        wg.name,
+			Since:       wg.since,
+			Duration:    now.Sub(wg.since),
+		})
+		gids = append(gids, wg.producerGID, wg.consumerGID)
+	}
+	w.mu.Unlock()
+
+	if len(stalls) == 0 {
+		return 0
+	}
+	stacks := goroutineStacks()
+	for i := range stalls {
+		stalls[i].ProducerStack = stacks[gids[2*i]]
+		if stalls[i].Kind == StallStalledProducer {
+			stalls[i].ConsumerStack = stacks[gids[2*i+1]]
+		}
+		w.onStall(stalls[i])
+	}
+	return len(stalls)
+}
+
+// watch starts watching the generator. Its `Func` waits for the
+// consumer until the first generator function call.
+func (w *Watchdog) watch(g *state) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.generators[g.id] = &watched{
+		name:  g.name,
+		kind:  StallAbandonedConsumer,
+		since: w.clock.Now(),
+	}
+}
+
+// started records the goroutine of the `Func`.
+func (w *Watchdog) started(id uint64) {
+	gid := currentGoroutineID()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if wg, ok := w.generators[id]; ok {
+		wg.producerGID = gid
+	}
+}
+
+// wait records that the consumer is about to wait for the `Func`, or
+// that the `Func` is about to wait for the consumer.
+func (w *Watchdog) wait(id uint64, kind StallKind) {
+	var gid uint64
+	if kind == StallStalledProducer {
+		gid = currentGoroutineID()
+	}
+	now := w.clock.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if wg, ok := w.generators[id]; ok {
+		wg.kind, wg.since, wg.reported = kind, now, false
+		wg.consumerGID = gid
+	}
+}
+
+func (w *Watchdog) unwatch(id uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.generators, id)
+}
+
+// consumerWaits is called when the consumer calls a generator function
+// that will wait for the `Func`. The returned function is called when
+// the generator function returns.
+func (g *state) consumerWaits() func() {
+	g.watchdog.wait(g.id, StallStalledProducer)
+	return func() {
+		// the `Func` may have returned and stopped being watched
+		g.watchdog.wait(g.id, StallAbandonedConsumer)
+	}
+}
+
+// currentGoroutineID parses the ID of the calling goroutine from the
+// header of its stack trace.
+func currentGoroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// goroutineStacks returns the stack traces of all the goroutines keyed
+// by their ID.
+func goroutineStacks() map[uint64]string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := make(map[uint64]string)
+	for _, trace := range strings.Split(string(buf), "\n\n") {
+		header := strings.TrimPrefix(trace, "goroutine ")
+		if i := strings.IndexByte(header, ' '); i >= 0 {
+			if id, err := strconv.ParseUint(header[:i], 10, 64); err == nil {
+				stacks[id] = trace
+			}
+		}
+	}
+	return stacks
+}
@@ -0,0 +1,186 @@
+package generator_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bmdelacruz/generator"
+)
+
+func TestWatchdog(t *testing.T) {
+	newWatchdog := func() (*generator.Watchdog, *manualClock, func() []generator.Stall) {
+		var (
+			mu     sync.Mutex
+			stalls []generator.Stall
+		)
+		clock := newManualClock()
+		w := generator.NewWatchdog(time.Second, func(s generator.Stall) {
+			mu.Lock()
+			defer mu.Unlock()
+			stalls = append(stalls, s)
+		}, clock)
+		return w, clock, func() []generator.Stall {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]generator.Stall(nil), stalls...)
+		}
+	}
+
+	t.Run(`abandoned consumer`, func(t *testing.T) {
+		w, clock, stalls := newWatchdog()
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				suspendedInYield(gc)
+				return nil, nil
+			},
+			generator.WithWatchdog(w),
+			generator.WithName("numbers"),
+		)
+		defer g.Return(nil)
+		g.Next(nil)
+
+		clock.advance(999 * time.Millisecond)
+		if n := w.Check(); n != 0 {
+			t.Fatalf("got: %v. wanted: 0", n)
+		}
+		clock.advance(time.Millisecond)
+		if n := w.Check(); n != 1 {
+			t.Fatalf("got: %v. wanted: 1", n)
+		}
+		if n := w.Check(); n != 0 {
+			t.Fatalf("got: %v reported again. wanted: 0", n)
+		}
+
+		s := stalls()[0]
+		if s.Kind != generator.StallAbandonedConsumer || s.GeneratorID != g.ID() || s.Name != "numbers" || s.Duration != time.Second {
+			t.Fatalf("got: %+v", s)
+		}
+		if !strings.Contains(s.ProducerStack, "suspendedInYield") || s.ConsumerStack != "" {
+			t.Fatalf("got: %q, %q. wanted: the stack of the Func", s.ProducerStack, s.ConsumerStack)
+		}
+	})
+	t.Run(`stalled producer`, func(t *testing.T) {
+		w, clock, stalls := newWatchdog()
+		release := make(chan struct{})
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				<-release
+				return 1, nil
+			},
+			generator.WithWatchdog(w),
+		)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			blockedInNext(g)
+		}()
+		var s generator.Stall
+		waitFor(t, func() bool {
+			// the Func may be reported as waiting for the consumer
+			// before the consumer calls Next
+			clock.advance(time.Second)
+			w.Check()
+			all := stalls()
+			s = all[len(all)-1]
+			return s.Kind == generator.StallStalledProducer
+		})
+		close(release)
+		<-done
+
+		if s.Kind != generator.StallStalledProducer || s.GeneratorID != g.ID() {
+			t.Fatalf("got: %+v", s)
+		}
+		if !strings.Contains(s.ConsumerStack, "blockedInNext") || !strings.Contains(s.ProducerStack, "chan receive") {
+			t.Fatalf("got: %q, %q. wanted: the stacks of both sides", s.ProducerStack, s.ConsumerStack)
+		}
+
+		clock.advance(time.Hour)
+		if n := w.Check(); n != 0 {
+			t.Fatalf("got: %v for a finished generator. wanted: 0", n)
+		}
+	})
+	t.Run(`resumed`, func(t *testing.T) {
+		w, clock, _ := newWatchdog()
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) {
+				for {
+					if _, shouldReturn, _ := gc.Yield(1); shouldReturn {
+						return nil, nil
+					}
+				}
+			},
+			generator.WithWatchdog(w),
+		)
+		defer g.Return(nil)
+
+		for i := 0; i < 3; i++ {
+			clock.advance(900 * time.Millisecond)
+			g.Next(nil)
+			if n := w.Check(); n != 0 {
+				t.Fatalf("got: %v. wanted: 0", n)
+			}
+		}
+	})
+	t.Run(`Run`, func(t *testing.T) {
+		w, clock, stalls := newWatchdog()
+		g := generator.New(
+			func(gc *generator.Controller) (interface{}, error) { return nil, nil },
+			generator.WithWatchdog(w),
+		)
+		defer g.Next(nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := make(chan error)
+		go func() {
+			errChan <- w.Run(ctx)
+		}()
+		clock.waitForTimers(1)
+		clock.advance(500 * time.Millisecond)
+		clock.waitForTimers(1)
+		clock.advance(500 * time.Millisecond)
+		clock.waitForTimers(1)
+		cancel()
+		if err := <-errChan; err != context.Canceled {
+			t.Fatalf("got: %v. wanted: %v", err, context.Canceled)
+		}
+		if s := stalls(); len(s) != 1 || s[0].Kind != generator.StallAbandonedConsumer {
+			t.Fatalf("got: %+v. wanted: the generator that was never consumed", s)
+		}
+	})
+	t.Run(`non-positive threshold`, func(t *testing.T) {
+		for _, threshold := range []time.Duration{0, -time.Second} {
+			func() {
+				defer func() {
+					if r := recover(); r != "generator: non-positive threshold for NewWatchdog" {
+						t.Fatalf("got: %v for %v. wanted: a panic", r, threshold)
+					}
+				}()
+				generator.NewWatchdog(threshold, func(generator.Stall) {}, newManualClock())
+			}()
+		}
+	})
+}
+
+func suspendedInYield(gc *generator.Controller) {
+	gc.Yield(1)
+}
+
+func blockedInNext(g *generator.Generator) {
+	g.Next(nil)
+}
+
+// waitFor calls cond until it returns true.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
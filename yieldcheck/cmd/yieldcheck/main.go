@@ -0,0 +1,18 @@
+// Command yieldcheck runs the yieldcheck analyzer. It can be run on its
+// own or by `go vet`:
+//
+//	yieldcheck ./...
+//	go vet -vettool=$(which yieldcheck) ./...
+//
+// See the yieldcheck package for what it reports.
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/bmdelacruz/generator/yieldcheck"
+)
+
+func main() {
+	singlechecker.Main(yieldcheck.Analyzer)
+}
@@ -0,0 +1,58 @@
+package a
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bmdelacruz/generator"
+)
+
+var errBad = errors.New("bad")
+
+func numbers() *generator.Generator {
+	return generator.New(
+		func(gc *generator.Controller) (interface{}, error) {
+			for i := 0; i < 5; i++ {
+				gc.Yield(i) // want `shouldReturn and error results of Controller.Yield are discarded`
+			}
+			gc.Error(errBad)                  // want `shouldReturn and error results of Controller.Error are discarded`
+			gc.YieldTimeout(5, time.Second)   // want `shouldReturn and error results of Controller.YieldTimeout are discarded`
+			v, _, err := gc.Yield(6)          // want `shouldReturn result of Controller.Yield is discarded`
+			_, shouldReturn, _ := gc.Yield(v) // want `error result of Controller.Yield is discarded`
+			if shouldReturn || err != nil {
+				return nil, err
+			}
+			if v, shouldReturn, err := gc.Yield(7); shouldReturn {
+				return v, nil
+			} else if err != nil {
+				return nil, err
+			}
+			return nil, nil
+		},
+	)
+}
+
+func consume(g *generator.Generator) error {
+	g.Next(nil)                                                          // want `error result of Generator.Next is discarded`
+	for v, isDone, _ := g.Next(nil); !isDone; v, isDone, _ = g.Next(v) { // want `error result of Generator.Next is discarded` `error result of Generator.Next is discarded`
+	}
+	var _, _, err = g.Next(nil)
+	_, _, _ = g.Next(nil)
+	g.Return(nil)
+	return err
+}
+
+func count(g *generator.Generator) (int, string, error) {
+	g.Next(nil) // want `error result of Generator.Next is discarded`
+	return 0, "", nil
+}
+
+func noError(g *generator.Generator, gc *generator.Controller) {
+	g.Next(nil) // want `error result of Generator.Next is discarded`
+	gc.Yield(1) // want `shouldReturn and error results of Controller.Yield are discarded`
+}
+
+func unnamedResults(gc *generator.Controller) (time.Time, error) {
+	gc.Yield(1) // want `shouldReturn and error results of Controller.Yield are discarded`
+	return time.Time{}, nil
+}
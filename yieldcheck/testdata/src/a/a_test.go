@@ -0,0 +1,19 @@
+package a
+
+import (
+	"testing"
+
+	"github.com/bmdelacruz/generator"
+)
+
+func TestNumbers(t *testing.T) {
+	g := numbers()
+	g.Next(nil)
+	if v, _, _ := g.Next(nil); v != 1 {
+		t.Fatal(v)
+	}
+	generator.New(func(gc *generator.Controller) (interface{}, error) {
+		gc.Yield(1)
+		return nil, nil
+	})
+}
@@ -0,0 +1,29 @@
+package generator
+
+import (
+	"context"
+	"time"
+)
+
+type Func func(controller *Controller) (interface{}, error)
+
+type Generator struct{}
+
+func New(generatorFunc Func) *Generator { return &Generator{} }
+
+func (g *Generator) Next(value interface{}) (interface{}, bool, error)   { return nil, true, nil }
+func (g *Generator) Return(value interface{}) (interface{}, bool, error) { return nil, true, nil }
+func (g *Generator) Error(err error) (interface{}, bool, error)          { return nil, true, nil }
+
+type Controller struct{}
+
+func (c *Controller) Yield(value interface{}) (interface{}, bool, error) { return nil, true, nil }
+func (c *Controller) Error(err error) (interface{}, bool, error)         { return nil, true, nil }
+
+func (c *Controller) YieldTimeout(value interface{}, d time.Duration) (interface{}, bool, error) {
+	return nil, true, nil
+}
+
+func (c *Controller) YieldContext(ctx context.Context, value interface{}) (interface{}, bool, error) {
+	return nil, true, nil
+}
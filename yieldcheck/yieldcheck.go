@@ -0,0 +1,291 @@
+// Package yieldcheck defines an analyzer that reports generator
+// function results that are discarded although they tell the caller
+// what to do next:
+//
+//   - the shouldReturn and error results of `Controller#Yield`,
+//     `Controller#YieldTimeout`, `Controller#YieldContext` and
+//     `Controller#Error`, which carry the consumer's `Generator#Return`
+//     and `Generator#Error` calls.
+//   - the error result of `Generator#Next`.
+//
+// When a call is used as a statement inside a function whose results
+// allow it, the analyzer suggests a fix that handles the results.
+//
+// Results can be discarded on purpose by assigning all of them to the
+// blank identifier, like `_, _, _ = gc.Yield(v)`. Test files are not
+// checked since tests often drive a generator step by step and check
+// the results in other ways.
+//
+// The analyzer can be run using `go vet` with the yieldcheck command:
+//
+//	go install github.com/bmdelacruz/generator/yieldcheck/cmd/yieldcheck@latest
+//	go vet -vettool=$(which yieldcheck) ./...
+//
+// The analyzer is a module of its own so that the generator module
+// doesn't depend on golang.org/x/tools.
+package yieldcheck
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const generatorPath = "github.com/bmdelacruz/generator"
+
+// Analyzer reports discarded results of the generator functions.
+var Analyzer = &analysis.Analyzer{
+	Name:     "yieldcheck",
+	Doc:      "report discarded shouldReturn and error results of generator functions",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// checked maps the checked methods to whether they're controller
+// functions. The shouldReturn result is only checked for those.
+var checked = map[string]map[string]bool{
+	"Controller": {"Yield": true, "YieldTimeout": true, "YieldContext": true, "Error": true},
+	"Generator":  {"Next": false},
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	nodes := []ast.Node{
+		(*ast.File)(nil),
+		(*ast.FuncDecl)(nil),
+		(*ast.FuncLit)(nil),
+		(*ast.ExprStmt)(nil),
+		(*ast.AssignStmt)(nil),
+		(*ast.ValueSpec)(nil),
+	}
+	insp.WithStack(nodes, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		switch n := n.(type) {
+		case *ast.File:
+			// skip the test files
+			return !strings.HasSuffix(pass.Fset.File(n.Pos()).Name(), "_test.go")
+		case *ast.ExprStmt:
+			if call, ok := n.X.(*ast.CallExpr); ok {
+				if typeName, method, ok := checkedMethod(pass, call); ok {
+					reportStmt(pass, n, call, typeName, method, enclosingResults(pass, stack))
+				}
+			}
+		case *ast.AssignStmt:
+			if len(n.Rhs) == 1 {
+				checkAssign(pass, n.Lhs, n.Rhs[0])
+			}
+		case *ast.ValueSpec:
+			if len(n.Values) == 1 {
+				lhs := make([]ast.Expr, len(n.Names))
+				for i, name := range n.Names {
+					lhs[i] = name
+				}
+				checkAssign(pass, lhs, n.Values[0])
+			}
+		}
+		return true
+	})
+	return nil, nil
+}
+
+// checkedMethod returns the type and the name of the method if the call
+// is a call of one of the checked methods.
+func checkedMethod(pass *analysis.Pass, call *ast.CallExpr) (string, string, bool) {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != generatorPath {
+		return "", "", false
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return "", "", false
+	}
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return "", "", false
+	}
+	typeName := named.Obj().Name()
+	if _, ok := checked[typeName][fn.Name()]; !ok {
+		return "", "", false
+	}
+	return typeName, fn.Name(), true
+}
+
+func checkAssign(pass *analysis.Pass, lhs []ast.Expr, rhs ast.Expr) {
+	call, ok := astutil.Unparen(rhs).(*ast.CallExpr)
+	if !ok || len(lhs) != 3 {
+		return
+	}
+	if isBlank(lhs[0]) && isBlank(lhs[1]) && isBlank(lhs[2]) {
+		// the results are discarded on purpose
+		return
+	}
+	typeName, method, ok := checkedMethod(pass, call)
+	if !ok {
+		return
+	}
+	isController := checked[typeName][method]
+	if isController && isBlank(lhs[1]) {
+		pass.Reportf(lhs[1].Pos(), "shouldReturn result of %s.%s is discarded", typeName, method)
+	}
+	if isBlank(lhs[2]) {
+		pass.Reportf(lhs[2].Pos(), "error result of %s.%s is discarded", typeName, method)
+	}
+}
+
+func isBlank(e ast.Expr) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && id.Name == "_"
+}
+
+// enclosingResults returns the results of the innermost function in the
+// stack.
+func enclosingResults(pass *analysis.Pass, stack []ast.Node) *types.Tuple {
+	for i := len(stack) - 1; i >= 0; i-- {
+		var t types.Type
+		switch f := stack[i].(type) {
+		case *ast.FuncDecl:
+			if obj := pass.TypesInfo.Defs[f.Name]; obj != nil {
+				t = obj.Type()
+			}
+		case *ast.FuncLit:
+			t = pass.TypesInfo.TypeOf(f)
+		default:
+			continue
+		}
+		if sig, ok := t.(*types.Signature); ok {
+			return sig.Results()
+		}
+		return nil
+	}
+	return nil
+}
+
+func reportStmt(
+	pass *analysis.Pass,
+	stmt *ast.ExprStmt,
+	call *ast.CallExpr,
+	typeName string,
+	method string,
+	results *types.Tuple,
+) {
+	d := analysis.Diagnostic{Pos: call.Pos(), End: call.End()}
+	if checked[typeName][method] {
+		d.Message = fmt.Sprintf("shouldReturn and error results of %s.%s are discarded", typeName, method)
+	} else {
+		d.Message = fmt.Sprintf("error result of %s.%s is discarded", typeName, method)
+	}
+
+	if fix, ok := suggestFix(pass, call, checked[typeName][method], results); ok {
+		d.SuggestedFixes = []analysis.SuggestedFix{{
+			Message:   "Handle the results",
+			TextEdits: []analysis.TextEdit{{Pos: stmt.Pos(), End: stmt.End(), NewText: fix}},
+		}}
+	}
+	pass.Report(d)
+}
+
+// suggestFix returns a statement that replaces the call and returns
+// from the enclosing function when the call returns shouldReturn equal
+// to true or an error. The statement can only be written when the last
+// result of the function is an error and the zero values of the other
+// results can be written. If the function returns (interface{}, error)
+// like a `Func`, the value received with shouldReturn is returned.
+func suggestFix(pass *analysis.Pass, call *ast.CallExpr, isController bool, results *types.Tuple) ([]byte, bool) {
+	if results == nil || results.Len() == 0 || !isError(results.At(results.Len()-1).Type()) {
+		return nil, false
+	}
+	zeros := make([]string, results.Len()-1)
+	for i := range zeros {
+		zero, ok := zeroValue(pass, results.At(i).Type())
+		if !ok {
+			return nil, false
+		}
+		zeros[i] = zero
+	}
+	returnErr := joinResults(zeros, "err")
+
+	var callText bytes.Buffer
+	if err := format.Node(&callText, pass.Fset, call); err != nil {
+		return nil, false
+	}
+
+	var fix string
+	switch {
+	case !isController:
+		fix = fmt.Sprintf("if _, _, err := %s; err != nil {\n\treturn %s\n}", callText.String(), returnErr)
+	case results.Len() == 2 && isEmptyInterface(results.At(0).Type()):
+		fix = fmt.Sprintf(
+			"if value, shouldReturn, err := %s; shouldReturn {\n\treturn value, nil\n} else if err != nil {\n\treturn nil, err\n}",
+			callText.String(),
+		)
+	default:
+		fix = fmt.Sprintf(
+			"if _, shouldReturn, err := %s; shouldReturn || err != nil {\n\treturn %s\n}",
+			callText.String(), returnErr,
+		)
+	}
+	return []byte(fix), true
+}
+
+func joinResults(zeros []string, last string) string {
+	var b bytes.Buffer
+	for _, z := range zeros {
+		b.WriteString(z)
+		b.WriteString(", ")
+	}
+	b.WriteString(last)
+	return b.String()
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+func isEmptyInterface(t types.Type) bool {
+	i, ok := t.Underlying().(*types.Interface)
+	return ok && i.NumMethods() == 0
+}
+
+// zeroValue returns how the zero value of the type is written in the
+// package, if it's simple enough.
+func zeroValue(pass *analysis.Pass, t types.Type) (string, bool) {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "false", true
+		case u.Info()&types.IsNumeric != 0:
+			return "0", true
+		case u.Info()&types.IsString != 0:
+			return `""`, true
+		}
+	case *types.Pointer, *types.Interface, *types.Slice, *types.Map, *types.Chan, *types.Signature:
+		return "nil", true
+	case *types.Struct:
+		if _, ok := t.(*types.Named); ok {
+			return types.TypeString(t, func(p *types.Package) string {
+				if p == pass.Pkg {
+					return ""
+				}
+				return p.Name()
+			}) + "{}", true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,13 @@
+package yieldcheck_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/bmdelacruz/generator/yieldcheck"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), yieldcheck.Analyzer, "a")
+}